@@ -0,0 +1,99 @@
+package routeplanner
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/yurachistic1/routeplanner-backend/routing"
+)
+
+// Direction is a maneuver of the directions of a route as it is given to
+// clients. Distance is in meters to the next maneuver and Index is the index
+// in the path of the point at which the maneuver is made.
+type Direction struct {
+	Action   string  `json:"action"`
+	Text     string  `json:"text"`
+	Distance float64 `json:"distance"`
+	Index    int     `json:"index"`
+}
+
+// Directions returns the directions for following the route with texts in
+// the locale.
+func directions(route routing.Route, loc *locale) []Direction {
+
+	maneuvers := route.Maneuvers()
+	loop := len(route.Path) > 1 && route.Path[0] == route.Path[len(route.Path)-1]
+
+	res := make([]Direction, len(maneuvers))
+
+	for i, m := range maneuvers {
+		res[i] = Direction{
+			Action:   m.Action.String(),
+			Text:     instruction(m, loop, loc),
+			Distance: m.Distance,
+			Index:    m.Index,
+		}
+	}
+
+	return res
+}
+
+// Instruction returns the text of a maneuver in the locale, loop is set when
+// the route ends where it started. Messages are looked up by action, the
+// variant naming the way is used if the way can be described.
+func instruction(m routing.Maneuver, loop bool, loc *locale) string {
+
+	key := m.Action.String()
+	data := messageData{
+		Direction: loc.Compass[compass(m.Bearing)],
+		Way:       wayDescription(m.Way, loc),
+		Distance:  formatDistance(m.Distance, loc),
+	}
+
+	switch {
+	case m.Action == routing.Arrive && loop:
+		key += ".loop"
+	case data.Way != "" && loc.has(key+".way"):
+		key += ".way"
+	}
+
+	return loc.text(key, data)
+}
+
+// WayDescription returns the name of the way or what kind of way it is, or
+// an empty string if neither is known.
+func wayDescription(way *routing.Way, loc *locale) string {
+
+	if way == nil {
+		return ""
+	}
+
+	if way.Name != "" {
+		return way.Name
+	}
+
+	return loc.Highways[way.Highway]
+}
+
+// Number of compass points directions use.
+const compassPoints = 8
+
+// Compass returns the index of the closest compass point to the bearing,
+// starting at north and going clockwise.
+func compass(bearing float64) int {
+	return int(math.Mod(bearing+180/compassPoints, 360)/(360/compassPoints)) % compassPoints
+}
+
+// FormatDistance returns the distance in meters rounded for reading, to 10 m
+// or to 100 m in kilometers from 1 km.
+func formatDistance(distance float64, loc *locale) string {
+
+	if distance < 995 {
+		return loc.text("meters", fmt.Sprintf("%.0f", math.Round(distance/10)*10))
+	}
+
+	km := strings.Replace(fmt.Sprintf("%.1f", distance/1000), ".", loc.Decimal, 1)
+
+	return loc.text("kilometers", km)
+}
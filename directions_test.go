@@ -0,0 +1,80 @@
+package routeplanner
+
+import (
+	"testing"
+
+	"github.com/yurachistic1/routeplanner-backend/routing"
+)
+
+func TestInstruction(t *testing.T) {
+
+	knole := &routing.Way{Highway: "footway", Name: "Knole Path"}
+	steps := &routing.Way{Highway: "steps"}
+	road := &routing.Way{Highway: "residential"}
+
+	cases := []struct {
+		m    routing.Maneuver
+		loop bool
+		want string
+	}{
+		{routing.Maneuver{Action: routing.Depart, Way: knole, Bearing: 10}, false, "Head north on Knole Path"},
+		{routing.Maneuver{Action: routing.Depart, Way: road, Bearing: 230}, false, "Head southwest"},
+		{routing.Maneuver{Action: routing.Left, Way: knole}, false, "Turn left onto Knole Path"},
+		{routing.Maneuver{Action: routing.SharpRight, Way: steps}, false, "Turn sharp right onto the steps"},
+		{routing.Maneuver{Action: routing.SlightLeft, Way: road}, false, "Turn slightly left"},
+		{routing.Maneuver{Action: routing.UTurn, Way: knole}, false, "Make a U-turn"},
+		{routing.Maneuver{Action: routing.Continue, Way: knole, Distance: 400}, false, "Continue onto Knole Path"},
+		{routing.Maneuver{Action: routing.Continue, Way: road, Distance: 396}, false, "Continue 400 m"},
+		{routing.Maneuver{Action: routing.TakeSteps, Way: steps}, false, "Take the steps"},
+		{routing.Maneuver{Action: routing.Arrive}, false, "Arrive at the destination"},
+		{routing.Maneuver{Action: routing.Arrive}, true, "Arrive back at the start"},
+	}
+
+	for _, c := range cases {
+		if got := instruction(c.m, c.loop, locales["en"]); got != c.want {
+			t.Errorf("instruction(%v, %v) == %q, want %q", c.m.Action, c.loop, got, c.want)
+		}
+	}
+}
+
+func TestCompass(t *testing.T) {
+
+	cases := []struct {
+		in   float64
+		want string
+	}{
+		{0, "north"},
+		{22, "north"},
+		{23, "northeast"},
+		{90, "east"},
+		{200, "south"},
+		{300, "northwest"},
+		{350, "north"},
+	}
+
+	for _, c := range cases {
+		if got := locales["en"].Compass[compass(c.in)]; got != c.want {
+			t.Errorf("compass(%v) == %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestFormatDistance(t *testing.T) {
+
+	cases := []struct {
+		in   float64
+		want string
+	}{
+		{4, "0 m"},
+		{396, "400 m"},
+		{994, "990 m"},
+		{1000, "1.0 km"},
+		{2460, "2.5 km"},
+	}
+
+	for _, c := range cases {
+		if got := formatDistance(c.in, locales["en"]); got != c.want {
+			t.Errorf("formatDistance(%v) == %q, want %q", c.in, got, c.want)
+		}
+	}
+}
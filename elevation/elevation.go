@@ -0,0 +1,111 @@
+// Package elevation samples terrain heights from digital elevation models
+// stored on the local disk, such as SRTM tiles and GeoTIFF files.
+package elevation
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrNoData is returned for locations that are not covered by any model or
+// where the model has a void.
+var ErrNoData = errors.New("no elevation data")
+
+// Provider returns the height in meters above sea level of a location.
+type Provider interface {
+	Elevation(lat, lon float64) (float64, error)
+}
+
+// Grid is an elevation model of samples spaced evenly in latitude and
+// longitude.
+type Grid struct {
+	// Lat and Lon of the first sample, which is the north west corner.
+	Lat float64
+	Lon float64
+
+	// LatStep and LonStep are the distances between samples in degrees.
+	LatStep float64
+	LonStep float64
+
+	Width  int
+	Height int
+
+	// Values holds the samples row by row from north to south, voids are NaN.
+	// Single precision keeps large models such as 1 arc second SRTM tiles
+	// small and is far more precise than the models themselves.
+	Values []float32
+}
+
+// Contains reports whether the location is within the samples of the grid.
+func (g *Grid) Contains(lat, lon float64) bool {
+	x, y := g.position(lat, lon)
+	return x >= 0 && y >= 0 && x <= float64(g.Width-1) && y <= float64(g.Height-1)
+}
+
+// Elevation interpolates the height of a location from the four surrounding
+// samples, voids among them are left out.
+func (g *Grid) Elevation(lat, lon float64) (float64, error) {
+
+	if !g.Contains(lat, lon) {
+		return 0, ErrNoData
+	}
+
+	x, y := g.position(lat, lon)
+
+	x0, y0 := int(x), int(y)
+	x1, y1 := minInt(x0+1, g.Width-1), minInt(y0+1, g.Height-1)
+	fx, fy := x-float64(x0), y-float64(y0)
+
+	var sum, total float64
+
+	for _, s := range []struct {
+		x, y   int
+		weight float64
+	}{
+		{x0, y0, (1 - fx) * (1 - fy)},
+		{x1, y0, fx * (1 - fy)},
+		{x0, y1, (1 - fx) * fy},
+		{x1, y1, fx * fy},
+	} {
+		if value := g.at(s.x, s.y); !math.IsNaN(value) && s.weight > 0 {
+			sum += value * s.weight
+			total += s.weight
+		}
+	}
+
+	if total == 0 {
+		return 0, ErrNoData
+	}
+
+	return sum / total, nil
+}
+
+// Position returns the fractional column and row of a location.
+func (g *Grid) position(lat, lon float64) (x, y float64) {
+	return (lon - g.Lon) / g.LonStep, (g.Lat - lat) / g.LatStep
+}
+
+func (g *Grid) at(x, y int) float64 {
+	return float64(g.Values[y*g.Width+x])
+}
+
+// Grids is a Provider that uses the first of the grids containing a location.
+type Grids []*Grid
+
+// Elevation returns the height of a location from the first grid that has
+// data for it.
+func (grids Grids) Elevation(lat, lon float64) (float64, error) {
+	for _, g := range grids {
+		if height, err := g.Elevation(lat, lon); err == nil {
+			return height, nil
+		}
+	}
+	return 0, ErrNoData
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
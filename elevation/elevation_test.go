@@ -0,0 +1,58 @@
+package elevation
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGridElevation(t *testing.T) {
+
+	// 3 x 3 samples 0.1 degrees apart with a void in the south east
+	g := &Grid{
+		Lat: 51.2, Lon: 0, LatStep: 0.1, LonStep: 0.1, Width: 3, Height: 3,
+		Values: []float32{
+			10, 20, 30,
+			20, 30, 40,
+			30, 40, float32(math.NaN()),
+		},
+	}
+
+	cases := []struct {
+		lat, lon float64
+		want     float64
+		err      error
+	}{
+		{51.2, 0, 10, nil},
+		{51.1, 0.1, 30, nil},
+		{51.15, 0.05, 20, nil},
+		{51.2, 0.15, 25, nil},
+		{51.0, 0.2, 0, ErrNoData},
+		{51.01, 0.19, 7.5 / 0.19, nil},
+		{51.3, 0, 0, ErrNoData},
+		{51.1, -0.01, 0, ErrNoData},
+	}
+
+	for _, c := range cases {
+		result, err := g.Elevation(c.lat, c.lon)
+
+		if err != c.err || math.Abs(result-c.want) > 1e-9 {
+			t.Errorf("Elevation(%v, %v) == %v, %v, want %v, %v", c.lat, c.lon, result, err, c.want, c.err)
+		}
+	}
+}
+
+func TestGrids(t *testing.T) {
+
+	west := &Grid{Lat: 1, Lon: 0, LatStep: 1, LonStep: 1, Width: 2, Height: 2, Values: []float32{1, 1, 1, 1}}
+	east := &Grid{Lat: 1, Lon: 1, LatStep: 1, LonStep: 1, Width: 2, Height: 2, Values: []float32{2, 2, 2, 2}}
+
+	grids := Grids{west, east}
+
+	if h, err := grids.Elevation(0.5, 1.5); h != 2 || err != nil {
+		t.Errorf("Elevation(0.5, 1.5) == %v, %v, want 2, nil", h, err)
+	}
+
+	if _, err := grids.Elevation(0.5, 2.5); err != ErrNoData {
+		t.Errorf("Elevation(0.5, 2.5) error == %v, want %v", err, ErrNoData)
+	}
+}
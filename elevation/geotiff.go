@@ -0,0 +1,429 @@
+package elevation
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// TIFF and GeoTIFF tags used by the reader.
+const (
+	tagImageWidth      = 256
+	tagImageLength     = 257
+	tagBitsPerSample   = 258
+	tagCompression     = 259
+	tagStripOffsets    = 273
+	tagSamplesPerPixel = 277
+	tagStripByteCounts = 279
+	tagTileWidth       = 322
+	tagSampleFormat    = 339
+	tagPixelScale      = 33550
+	tagTiepoint        = 33922
+	tagGeoKeys         = 34735
+	tagNoData          = 42113
+)
+
+// GeoTIFF keys used by the reader.
+const (
+	keyModelType  = 1024
+	keyRasterType = 1025
+
+	modelTypeProjected = 1
+	rasterPixelIsPoint = 2
+)
+
+// Sample formats.
+const (
+	formatUint  = 1
+	formatInt   = 2
+	formatFloat = 3
+)
+
+// OpenGeoTIFF reads the GeoTIFF file at path, see ReadGeoTIFF.
+func OpenGeoTIFF(path string) (*Grid, error) {
+
+	f, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	grid, err := ReadGeoTIFF(f)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return grid, nil
+}
+
+// ReadGeoTIFF reads an elevation model from a GeoTIFF. Only the first image
+// is read and it has to be a single band of uncompressed strips of integer or
+// floating point samples, georeferenced by a tiepoint and pixel scale in
+// latitude and longitude. That covers DEMs as distributed by most sources
+// once they are converted with gdal_translate -co COMPRESS=NONE.
+func ReadGeoTIFF(r io.ReaderAt) (*Grid, error) {
+
+	header := make([]byte, 8)
+
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, unexpected(err)
+	}
+
+	var order binary.ByteOrder
+
+	switch string(header[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, errors.New("not a tiff file")
+	}
+
+	if order.Uint16(header[2:]) != 42 {
+		return nil, errors.New("unsupported tiff version")
+	}
+
+	t := &tiff{r: r, order: order}
+
+	if err := t.readIFD(int64(order.Uint32(header[4:]))); err != nil {
+		return nil, err
+	}
+
+	return t.grid()
+}
+
+// Tiff holds the entries of the first image file directory.
+type tiff struct {
+	r       io.ReaderAt
+	order   binary.ByteOrder
+	entries map[uint16]entry
+}
+
+// Entry is a field of an image file directory, value holds the raw value or
+// the offset of the values if they do not fit in four bytes.
+type entry struct {
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+// Sizes of the field types in bytes.
+var typeSizes = map[uint16]int{
+	1:  1, // BYTE
+	2:  1, // ASCII
+	3:  2, // SHORT
+	4:  4, // LONG
+	11: 4, // FLOAT
+	12: 8, // DOUBLE
+}
+
+func (t *tiff) readIFD(offset int64) error {
+
+	count := make([]byte, 2)
+
+	if _, err := t.r.ReadAt(count, offset); err != nil {
+		return unexpected(err)
+	}
+
+	n := int(t.order.Uint16(count))
+	data := make([]byte, 12*n)
+
+	if _, err := t.r.ReadAt(data, offset+2); err != nil {
+		return unexpected(err)
+	}
+
+	t.entries = make(map[uint16]entry, n)
+
+	for i := 0; i < n; i++ {
+		e := data[12*i:]
+		t.entries[t.order.Uint16(e)] = entry{
+			typ:   t.order.Uint16(e[2:]),
+			count: t.order.Uint32(e[4:]),
+			value: e[8:12],
+		}
+	}
+
+	return nil
+}
+
+// Bytes returns the raw values of a field.
+func (t *tiff) bytes(tag uint16) ([]byte, error) {
+
+	e, ok := t.entries[tag]
+
+	if !ok {
+		return nil, nil
+	}
+
+	size, ok := typeSizes[e.typ]
+
+	if !ok {
+		return nil, fmt.Errorf("unsupported type %d of tiff tag %d", e.typ, tag)
+	}
+
+	length := size * int(e.count)
+
+	if length <= 4 {
+		return e.value[:length], nil
+	}
+
+	data := make([]byte, length)
+
+	if _, err := t.r.ReadAt(data, int64(t.order.Uint32(e.value))); err != nil {
+		return nil, unexpected(err)
+	}
+
+	return data, nil
+}
+
+// Numbers returns the values of a numeric field, def if it is missing.
+func (t *tiff) numbers(tag uint16, def ...float64) ([]float64, error) {
+
+	data, err := t.bytes(tag)
+
+	if err != nil || data == nil {
+		return def, err
+	}
+
+	e := t.entries[tag]
+	values := make([]float64, e.count)
+
+	for i := range values {
+		switch e.typ {
+		case 1:
+			values[i] = float64(data[i])
+		case 3:
+			values[i] = float64(t.order.Uint16(data[2*i:]))
+		case 4:
+			values[i] = float64(t.order.Uint32(data[4*i:]))
+		case 11:
+			values[i] = float64(math.Float32frombits(t.order.Uint32(data[4*i:])))
+		case 12:
+			values[i] = math.Float64frombits(t.order.Uint64(data[8*i:]))
+		default:
+			return nil, fmt.Errorf("tiff tag %d is not numeric", tag)
+		}
+	}
+
+	return values, nil
+}
+
+// Number returns the first value of a numeric field.
+func (t *tiff) number(tag uint16, def float64) (float64, error) {
+	values, err := t.numbers(tag, def)
+	if err != nil {
+		return 0, err
+	}
+	if len(values) == 0 {
+		return 0, fmt.Errorf("tiff tag %d is empty", tag)
+	}
+	return values[0], nil
+}
+
+// GeoKeys returns the values of the short GeoTIFF keys.
+func (t *tiff) geoKeys() (map[int]int, error) {
+
+	dir, err := t.numbers(tagGeoKeys)
+	keys := make(map[int]int)
+
+	if err != nil || len(dir) < 4 {
+		return keys, err
+	}
+
+	for i := 4; i+3 < len(dir); i += 4 {
+		// keys with a location of 0 hold the value directly
+		if dir[i+1] == 0 {
+			keys[int(dir[i])] = int(dir[i+3])
+		}
+	}
+
+	return keys, nil
+}
+
+func (t *tiff) grid() (*Grid, error) {
+
+	if _, tiled := t.entries[tagTileWidth]; tiled {
+		return nil, errors.New("tiled tiff is not supported")
+	}
+
+	var width, height, bits, compression, samples, format float64
+	var err error
+
+	for _, f := range []struct {
+		value *float64
+		tag   uint16
+		def   float64
+	}{
+		{&width, tagImageWidth, 0},
+		{&height, tagImageLength, 0},
+		{&bits, tagBitsPerSample, 1},
+		{&compression, tagCompression, 1},
+		{&samples, tagSamplesPerPixel, 1},
+		{&format, tagSampleFormat, formatUint},
+	} {
+		if *f.value, err = t.number(f.tag, f.def); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case width < 2 || height < 2:
+		return nil, errors.New("invalid tiff dimensions")
+	case compression != 1:
+		return nil, errors.New("compressed tiff is not supported")
+	case samples != 1:
+		return nil, errors.New("tiff has more than one band")
+	}
+
+	sample, err := sampleReader(t.order, int(bits), int(format))
+
+	if err != nil {
+		return nil, err
+	}
+
+	grid, err := t.georeference(int(width), int(height))
+
+	if err != nil {
+		return nil, err
+	}
+
+	noData := math.NaN()
+
+	if data, err := t.bytes(tagNoData); err == nil && data != nil {
+		value := strings.TrimRight(string(data), "\x00 ")
+		if noData, err = strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("invalid tiff nodata %q", value)
+		}
+	}
+
+	offsets, err := t.numbers(tagStripOffsets)
+
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := t.numbers(tagStripByteCounts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(offsets) == 0 || len(offsets) != len(counts) {
+		return nil, errors.New("invalid tiff strips")
+	}
+
+	// strips hold consecutive rows, so they can simply be joined
+	size := int(bits) / 8
+	data := make([]byte, 0, grid.Width*grid.Height*size)
+
+	for i := range offsets {
+		strip := make([]byte, int(counts[i]))
+
+		if _, err := t.r.ReadAt(strip, int64(offsets[i])); err != nil {
+			return nil, unexpected(err)
+		}
+
+		data = append(data, strip...)
+	}
+
+	if len(data) < len(grid.Values)*size {
+		return nil, errors.New("tiff strips are too short")
+	}
+
+	for i := range grid.Values {
+		value := sample(data[i*size:])
+
+		if value == noData || math.IsNaN(value) {
+			value = math.NaN()
+		}
+
+		grid.Values[i] = float32(value)
+	}
+
+	return grid, nil
+}
+
+// Georeference returns an empty grid placed by the tiepoint and pixel scale.
+func (t *tiff) georeference(width, height int) (*Grid, error) {
+
+	scale, err := t.numbers(tagPixelScale)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tiepoint, err := t.numbers(tagTiepoint)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(scale) < 2 || len(tiepoint) < 6 || scale[0] <= 0 || scale[1] <= 0 {
+		return nil, errors.New("tiff is not georeferenced")
+	}
+
+	keys, err := t.geoKeys()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if keys[keyModelType] == modelTypeProjected {
+		return nil, errors.New("projected tiff is not supported, only latitude and longitude")
+	}
+
+	// the tiepoint places pixel (i, j) at (x, y)
+	lon := tiepoint[3] - tiepoint[0]*scale[0]
+	lat := tiepoint[4] + tiepoint[1]*scale[1]
+
+	// by default pixels are areas and their values apply to the centre
+	if keys[keyRasterType] != rasterPixelIsPoint {
+		lon += scale[0] / 2
+		lat -= scale[1] / 2
+	}
+
+	return &Grid{
+		Lat:     lat,
+		Lon:     lon,
+		LatStep: scale[1],
+		LonStep: scale[0],
+		Width:   width,
+		Height:  height,
+		Values:  make([]float32, width*height),
+	}, nil
+}
+
+// SampleReader returns a function decoding a single sample.
+func sampleReader(order binary.ByteOrder, bits, format int) (func([]byte) float64, error) {
+
+	switch {
+	case bits == 16 && format == formatUint:
+		return func(b []byte) float64 { return float64(order.Uint16(b)) }, nil
+	case bits == 16 && format == formatInt:
+		return func(b []byte) float64 { return float64(int16(order.Uint16(b))) }, nil
+	case bits == 32 && format == formatInt:
+		return func(b []byte) float64 { return float64(int32(order.Uint32(b))) }, nil
+	case bits == 32 && format == formatFloat:
+		return func(b []byte) float64 { return float64(math.Float32frombits(order.Uint32(b))) }, nil
+	case bits == 64 && format == formatFloat:
+		return func(b []byte) float64 { return math.Float64frombits(order.Uint64(b)) }, nil
+	}
+
+	return nil, fmt.Errorf("unsupported tiff samples of %d bits in format %d", bits, format)
+}
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
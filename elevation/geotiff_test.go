@@ -0,0 +1,184 @@
+package elevation
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"sort"
+	"testing"
+)
+
+// Field of a test tiff, values are uint16, uint32, float64 or string slices.
+type field struct {
+	tag    uint16
+	values interface{}
+}
+
+// encodeTIFF returns a single image tiff of the given samples in one strip,
+// the value of a StripOffsets field is filled in.
+func encodeTIFF(order binary.ByteOrder, samples interface{}, fields ...field) []byte {
+
+	var pixels bytes.Buffer
+	binary.Write(&pixels, order, samples)
+
+	sort.Slice(fields, func(i, j int) bool { return fields[i].tag < fields[j].tag })
+
+	// header, directory, pixels and then the values that do not fit in an
+	// entry
+	ifd := 8
+	strip := ifd + 2 + 12*len(fields) + 4
+	extra := strip + pixels.Len()
+
+	var head, tail bytes.Buffer
+
+	if order == binary.LittleEndian {
+		head.WriteString("II")
+	} else {
+		head.WriteString("MM")
+	}
+
+	binary.Write(&head, order, uint16(42))
+	binary.Write(&head, order, uint32(ifd))
+	binary.Write(&head, order, uint16(len(fields)))
+
+	for _, f := range fields {
+		if f.tag == tagStripOffsets {
+			f.values = []uint32{uint32(strip)}
+		}
+
+		var typ uint16
+		var data bytes.Buffer
+		var count int
+
+		switch v := f.values.(type) {
+		case []uint16:
+			typ, count = 3, len(v)
+			binary.Write(&data, order, v)
+		case []uint32:
+			typ, count = 4, len(v)
+			binary.Write(&data, order, v)
+		case []float64:
+			typ, count = 12, len(v)
+			binary.Write(&data, order, v)
+		case string:
+			typ, count = 2, len(v)+1
+			data.WriteString(v + "\x00")
+		}
+
+		binary.Write(&head, order, f.tag)
+		binary.Write(&head, order, typ)
+		binary.Write(&head, order, uint32(count))
+
+		if data.Len() <= 4 {
+			head.Write(append(data.Bytes(), make([]byte, 4-data.Len())...))
+		} else {
+			binary.Write(&head, order, uint32(extra+tail.Len()))
+			tail.Write(data.Bytes())
+		}
+	}
+
+	binary.Write(&head, order, uint32(0))
+	head.Write(pixels.Bytes())
+	head.Write(tail.Bytes())
+
+	return head.Bytes()
+}
+
+// demFields returns the fields of a width by height image of int16 samples
+// with the north west corner at 52, 0 and 0.5 degree pixels. Fields in
+// extra are added or replace the defaults, nil values remove them.
+func demFields(width, height int, extra ...field) []field {
+
+	fields := []field{
+		{tagStripOffsets, []uint32{0}},
+		{tagImageWidth, []uint16{uint16(width)}},
+		{tagImageLength, []uint16{uint16(height)}},
+		{tagBitsPerSample, []uint16{16}},
+		{tagSampleFormat, []uint16{formatInt}},
+		{tagStripByteCounts, []uint32{uint32(2 * width * height)}},
+		{tagPixelScale, []float64{0.5, 0.5, 0}},
+		{tagTiepoint, []float64{0, 0, 0, 0, 52, 0}},
+	}
+
+	for _, e := range extra {
+		replaced := false
+		for i := range fields {
+			if fields[i].tag == e.tag {
+				fields[i], replaced = e, true
+			}
+		}
+		if !replaced {
+			fields = append(fields, e)
+		}
+	}
+
+	result := fields[:0]
+	for _, f := range fields {
+		if f.values != nil {
+			result = append(result, f)
+		}
+	}
+
+	return result
+}
+
+func TestReadGeoTIFF(t *testing.T) {
+
+	samples := []int16{
+		100, 200, 300,
+		100, 200, -9999,
+		-10, 0, 10,
+	}
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		data := encodeTIFF(order, samples, demFields(3, 3, field{tagNoData, "-9999"})...)
+
+		grid, err := ReadGeoTIFF(bytes.NewReader(data))
+
+		if err != nil {
+			t.Fatalf("%v: ReadGeoTIFF() returned %v", order, err)
+		}
+
+		// pixels are areas, so samples apply to their centres
+		if grid.Lat != 51.75 || grid.Lon != 0.25 || grid.LatStep != 0.5 || grid.Width != 3 || grid.Height != 3 {
+			t.Errorf("%v: ReadGeoTIFF() == %+v", order, grid)
+		}
+
+		if !math.IsNaN(float64(grid.Values[5])) || grid.Values[6] != -10 || grid.Values[1] != 200 {
+			t.Errorf("%v: ReadGeoTIFF() values == %v", order, grid.Values)
+		}
+	}
+
+	// pixels are points
+	keys := field{tagGeoKeys, []uint16{1, 1, 0, 1, keyRasterType, 0, 1, rasterPixelIsPoint}}
+	grid, err := ReadGeoTIFF(bytes.NewReader(encodeTIFF(binary.LittleEndian, samples, demFields(3, 3, keys)...)))
+
+	if err != nil || grid.Lat != 52 || grid.Lon != 0 {
+		t.Errorf("ReadGeoTIFF() of points == %+v, %v", grid, err)
+	}
+}
+
+func TestReadGeoTIFFErrors(t *testing.T) {
+
+	samples := []int16{1, 2, 3, 4}
+
+	cases := []struct {
+		name string
+		data []byte
+	}{
+		{"not a tiff", []byte("GIF89a..")},
+		{"truncated", encodeTIFF(binary.LittleEndian, samples, demFields(2, 2)...)[:20]},
+		{"compressed", encodeTIFF(binary.LittleEndian, samples, demFields(2, 2, field{tagCompression, []uint16{5}})...)},
+		{"bands", encodeTIFF(binary.LittleEndian, samples, demFields(2, 2, field{tagSamplesPerPixel, []uint16{3}})...)},
+		{"tiled", encodeTIFF(binary.LittleEndian, samples, demFields(2, 2, field{tagTileWidth, []uint16{256}})...)},
+		{"projected", encodeTIFF(binary.LittleEndian, samples, demFields(2, 2, field{tagGeoKeys, []uint16{1, 1, 0, 1, keyModelType, 0, 1, modelTypeProjected}})...)},
+		{"not georeferenced", encodeTIFF(binary.LittleEndian, samples, demFields(2, 2, field{tagTiepoint, nil})...)},
+		{"short strip", encodeTIFF(binary.LittleEndian, samples[:2], demFields(2, 2, field{tagStripByteCounts, []uint32{4}})...)},
+	}
+
+	for _, c := range cases {
+		if _, err := ReadGeoTIFF(bytes.NewReader(c.data)); err == nil {
+			t.Errorf("%s: ReadGeoTIFF() returned no error", c.name)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package elevation
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Local is a Provider reading elevation models from a directory. SRTM tiles
+// are used where they are available and GeoTIFF files, with a .tif or .tiff
+// extension, elsewhere. GeoTIFF files are all loaded on first use, files that
+// cannot be read are skipped.
+type Local struct {
+	srtm *SRTM
+
+	dir   string
+	once  sync.Once
+	grids Grids
+	err   error
+}
+
+// NewLocal returns a Local provider reading models from dir.
+func NewLocal(dir string) *Local {
+	return &Local{srtm: NewSRTM(dir), dir: dir}
+}
+
+// Elevation returns the height of a location.
+func (l *Local) Elevation(lat, lon float64) (float64, error) {
+
+	height, err := l.srtm.Elevation(lat, lon)
+
+	if err != ErrNoData {
+		return height, err
+	}
+
+	l.once.Do(l.load)
+
+	height, err = l.grids.Elevation(lat, lon)
+
+	// a file that could not be read may have covered the location
+	if err == ErrNoData && l.err != nil {
+		return 0, l.err
+	}
+
+	return height, err
+}
+
+func (l *Local) load() {
+
+	files, err := filepath.Glob(filepath.Join(l.dir, "*"))
+
+	if err != nil {
+		l.err = err
+		return
+	}
+
+	for _, file := range files {
+		ext := strings.ToLower(filepath.Ext(file))
+
+		if ext != ".tif" && ext != ".tiff" {
+			continue
+		}
+
+		grid, err := OpenGeoTIFF(file)
+
+		if err != nil {
+			if l.err == nil {
+				l.err = fmt.Errorf("%s: %w", filepath.Base(file), err)
+			}
+			continue
+		}
+
+		l.grids = append(l.grids, grid)
+	}
+}
@@ -0,0 +1,58 @@
+package elevation
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+
+	dir := t.TempDir()
+
+	// the tiff covers 50.5 to 52 and 0 to 1.5, the tile 51 to 52 and 0 to 1
+	tif := encodeTIFF(binary.LittleEndian, []int16{
+		5, 5, 5,
+		5, 5, 5,
+		5, 5, 5,
+	}, demFields(3, 3)...)
+
+	files := map[string][]byte{
+		"N51E000.hgt": hgt([]int16{100, 100, 100, 100}),
+		"dem.tif":     tif,
+		"notes.txt":   []byte("not a model"),
+		"broken.tif":  []byte("not a tiff"),
+	}
+
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l := NewLocal(dir)
+
+	cases := []struct {
+		lat, lon float64
+		want     float64
+		err      error
+	}{
+		{51.5, 0.5, 100, nil},
+		{50.75, 1.25, 5, nil},
+	}
+
+	for _, c := range cases {
+		result, err := l.Elevation(c.lat, c.lon)
+
+		if err != c.err || result != c.want {
+			t.Errorf("Elevation(%v, %v) == %v, %v, want %v, %v", c.lat, c.lon, result, err, c.want, c.err)
+		}
+	}
+
+	// the broken file may have covered locations without data
+	if _, err := l.Elevation(40, 10); err == nil || err == ErrNoData || !strings.Contains(err.Error(), "broken.tif") {
+		t.Errorf("Elevation(40, 10) error == %v, want the error reading broken.tif", err)
+	}
+}
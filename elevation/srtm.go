@@ -0,0 +1,175 @@
+package elevation
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Value of SRTM samples without data.
+const srtmVoid = -32768
+
+// SRTM is a Provider reading SRTM .hgt tiles from a directory. Tiles are
+// named after their south west corner, e.g. N51E000.hgt, and are loaded the
+// first time they are needed.
+type SRTM struct {
+	Dir string
+
+	// MaxTiles is the number of tiles kept in memory, the least recently used
+	// ones are dropped first. Zero means no limit.
+	MaxTiles int
+
+	mu    sync.Mutex
+	tiles map[string]*Grid
+
+	// used lists the names of the loaded tiles, most recently used last
+	used []string
+}
+
+// NewSRTM returns an SRTM provider reading tiles from dir. A 1 arc second
+// tile takes about 50 MB, at most 4 of them are kept which is enough for
+// routes crossing the corner of a tile.
+func NewSRTM(dir string) *SRTM {
+	return &SRTM{Dir: dir, MaxTiles: 4, tiles: make(map[string]*Grid)}
+}
+
+// Elevation returns the height of a location from the tile covering it.
+// ErrNoData is returned if there is no such tile in the directory.
+func (s *SRTM) Elevation(lat, lon float64) (float64, error) {
+
+	tile, err := s.tile(int(math.Floor(lat)), int(math.Floor(lon)))
+
+	if err != nil {
+		return 0, err
+	}
+
+	if tile == nil {
+		return 0, ErrNoData
+	}
+
+	return tile.Elevation(lat, lon)
+}
+
+// Tile returns the loaded tile with the south west corner at lat, lon or nil
+// if there is no file for it. Missing tiles are remembered too.
+func (s *SRTM) tile(lat, lon int) (*Grid, error) {
+
+	name := hgtName(lat, lon)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tiles == nil {
+		s.tiles = make(map[string]*Grid)
+	}
+
+	if tile, ok := s.tiles[name]; ok {
+		if tile != nil {
+			s.touch(name)
+		}
+		return tile, nil
+	}
+
+	f, err := os.Open(filepath.Join(s.Dir, name))
+
+	if os.IsNotExist(err) {
+		s.tiles[name] = nil
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	tile, err := ReadHGT(f, lat, lon)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	s.tiles[name] = tile
+	s.touch(name)
+
+	for s.MaxTiles > 0 && len(s.used) > s.MaxTiles {
+		delete(s.tiles, s.used[0])
+		s.used = s.used[1:]
+	}
+
+	return tile, nil
+}
+
+// Touch marks the loaded tile as the most recently used one.
+func (s *SRTM) touch(name string) {
+
+	for i, used := range s.used {
+		if used == name {
+			s.used = append(s.used[:i], s.used[i+1:]...)
+			break
+		}
+	}
+
+	s.used = append(s.used, name)
+}
+
+// ReadHGT reads an SRTM tile with the south west corner at lat, lon. Tiles
+// are square grids of big endian 16 bit samples covering one degree, 1201
+// samples wide for 3 arc second and 3601 for 1 arc second resolution.
+func ReadHGT(r io.Reader, lat, lon int) (*Grid, error) {
+
+	data, err := ioutil.ReadAll(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	side := int(math.Sqrt(float64(len(data) / 2)))
+
+	if side < 2 || side*side*2 != len(data) {
+		return nil, fmt.Errorf("invalid hgt size of %d bytes", len(data))
+	}
+
+	grid := &Grid{
+		Lat:     float64(lat + 1),
+		Lon:     float64(lon),
+		LatStep: 1 / float64(side-1),
+		LonStep: 1 / float64(side-1),
+		Width:   side,
+		Height:  side,
+		Values:  make([]float32, side*side),
+	}
+
+	for i := range grid.Values {
+		sample := int16(binary.BigEndian.Uint16(data[2*i:]))
+
+		if sample == srtmVoid {
+			grid.Values[i] = float32(math.NaN())
+		} else {
+			grid.Values[i] = float32(sample)
+		}
+	}
+
+	return grid, nil
+}
+
+// HgtName returns the name of the tile with the south west corner at lat, lon.
+func hgtName(lat, lon int) string {
+
+	ns, ew := 'N', 'E'
+
+	if lat < 0 {
+		ns, lat = 'S', -lat
+	}
+
+	if lon < 0 {
+		ew, lon = 'W', -lon
+	}
+
+	return fmt.Sprintf("%c%02d%c%03d.hgt", ns, lat, ew, lon)
+}
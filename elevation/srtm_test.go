@@ -0,0 +1,106 @@
+package elevation
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+// hgt encodes samples as an SRTM tile.
+func hgt(samples []int16) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.BigEndian, samples)
+	return b.Bytes()
+}
+
+func TestHgtName(t *testing.T) {
+
+	cases := []struct {
+		lat, lon int
+		want     string
+	}{
+		{51, 0, "N51E000.hgt"},
+		{51, -1, "N51W001.hgt"},
+		{-34, 151, "S34E151.hgt"},
+		{-1, -80, "S01W080.hgt"},
+	}
+
+	for _, c := range cases {
+		if result := hgtName(c.lat, c.lon); result != c.want {
+			t.Errorf("hgtName(%v, %v) == %v, want %v", c.lat, c.lon, result, c.want)
+		}
+	}
+}
+
+func TestReadHGT(t *testing.T) {
+
+	// 3 x 3 samples half a degree apart
+	grid, err := ReadHGT(bytes.NewReader(hgt([]int16{
+		100, 200, 300,
+		100, 200, srtmVoid,
+		-10, 0, 10,
+	})), 51, 0)
+
+	if err != nil {
+		t.Fatalf("ReadHGT() returned %v", err)
+	}
+
+	if grid.Lat != 52 || grid.Lon != 0 || grid.LatStep != 0.5 || grid.Width != 3 {
+		t.Errorf("ReadHGT() == %+v", grid)
+	}
+
+	if !math.IsNaN(float64(grid.Values[5])) || grid.Values[6] != -10 {
+		t.Errorf("ReadHGT() values == %v", grid.Values)
+	}
+
+	if _, err := ReadHGT(bytes.NewReader(make([]byte, 17)), 51, 0); err == nil {
+		t.Errorf("ReadHGT() returned no error for a tile of invalid size")
+	}
+}
+
+func TestSRTM(t *testing.T) {
+
+	dir := t.TempDir()
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "N51E000.hgt"), hgt([]int16{100, 200, 300, 400}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSRTM(dir)
+
+	if h, err := s.Elevation(51.5, 0.5); h != 250 || err != nil {
+		t.Errorf("Elevation(51.5, 0.5) == %v, %v, want 250, nil", h, err)
+	}
+
+	if _, err := s.Elevation(51.5, -0.5); err != ErrNoData {
+		t.Errorf("Elevation(51.5, -0.5) error == %v, want %v", err, ErrNoData)
+	}
+}
+
+func TestSRTMMaxTiles(t *testing.T) {
+
+	dir := t.TempDir()
+
+	for _, name := range []string{"N51E000.hgt", "N51E001.hgt", "N51E002.hgt"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), hgt([]int16{100, 200, 300, 400}), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewSRTM(dir)
+	s.MaxTiles = 2
+
+	for _, lon := range []float64{0.5, 1.5, 0.5, 2.5} {
+		if h, err := s.Elevation(51.5, lon); h != 250 || err != nil {
+			t.Errorf("Elevation(51.5, %v) == %v, %v, want 250, nil", lon, h, err)
+		}
+	}
+
+	// the second tile was used least recently
+	if _, ok := s.tiles["N51E001.hgt"]; ok || len(s.tiles) != 2 {
+		t.Errorf("tiles == %v, want N51E000.hgt and N51E002.hgt", s.used)
+	}
+}
@@ -0,0 +1,245 @@
+package routeplanner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Base types of FIT fields.
+const (
+	fitEnum   byte = 0x00
+	fitString byte = 0x07
+	fitUint16 byte = 0x84
+	fitSint32 byte = 0x85
+	fitUint32 byte = 0x86
+)
+
+// Global numbers of the FIT messages making up a course file.
+const (
+	fitFileId      uint16 = 0
+	fitLap         uint16 = 19
+	fitRecord      uint16 = 20
+	fitEvent       uint16 = 21
+	fitCourse      uint16 = 31
+	fitCoursePoint uint16 = 32
+)
+
+// Course point types.
+const (
+	fitLeft  = 6
+	fitRight = 7
+)
+
+// Length of names of FIT courses and course points, including the
+// terminating zero.
+const fitNameSize = 16
+
+// Start of FIT timestamps as a unix time, 1989-12-31 00:00 UTC.
+const fitEpoch = 631065600
+
+// FitField is a field of a FIT message, Value is a uint8, uint16, int32,
+// uint32 or string matching the base type.
+type fitField struct {
+	Num   byte
+	Type  byte
+	Value interface{}
+}
+
+func (f fitField) size() byte {
+	switch f.Type {
+	case fitString:
+		return fitNameSize
+	case fitUint16:
+		return 2
+	case fitSint32, fitUint32:
+		return 4
+	}
+	return 1
+}
+
+// FitWriter collects the messages of a FIT file. Every kind of message has its
+// own local message type that is defined when it is first written.
+type fitWriter struct {
+	data   bytes.Buffer
+	locals map[uint16]byte
+}
+
+func (fw *fitWriter) message(global uint16, fields ...fitField) {
+
+	local, ok := fw.locals[global]
+
+	if !ok {
+		local = byte(len(fw.locals))
+		fw.locals[global] = local
+
+		// definition message, little endian
+		fw.data.Write([]byte{0x40 | local, 0, 0})
+		binary.Write(&fw.data, binary.LittleEndian, global)
+		fw.data.WriteByte(byte(len(fields)))
+
+		for _, f := range fields {
+			fw.data.Write([]byte{f.Num, f.size(), f.Type})
+		}
+	}
+
+	fw.data.WriteByte(local)
+
+	for _, f := range fields {
+		if s, ok := f.Value.(string); ok {
+			name := make([]byte, fitNameSize)
+			copy(name[:fitNameSize-1], s)
+			fw.data.Write(name)
+			continue
+		}
+		binary.Write(&fw.data, binary.LittleEndian, f.Value)
+	}
+}
+
+// WriteTo writes the FIT file with its header and checksum.
+func (fw *fitWriter) WriteTo(w io.Writer) (int64, error) {
+
+	var file bytes.Buffer
+
+	file.Write([]byte{14, 0x20})
+	binary.Write(&file, binary.LittleEndian, uint16(2100))
+	binary.Write(&file, binary.LittleEndian, uint32(fw.data.Len()))
+	file.WriteString(".FIT")
+	binary.Write(&file, binary.LittleEndian, fitCRC(0, file.Bytes()))
+
+	file.Write(fw.data.Bytes())
+	binary.Write(&file, binary.LittleEndian, fitCRC(0, file.Bytes()))
+
+	return file.WriteTo(w)
+}
+
+var fitCRCTable = [16]uint16{
+	0x0000, 0xCC01, 0xD801, 0x1400, 0xF001, 0x3C00, 0x2800, 0xE401,
+	0xA001, 0x6C00, 0x7800, 0xB401, 0x5000, 0x9C01, 0x8801, 0x4400,
+}
+
+// FitCRC returns the FIT checksum of the data continuing from crc.
+func fitCRC(crc uint16, data []byte) uint16 {
+
+	for _, b := range data {
+		tmp := fitCRCTable[crc&0xf]
+		crc = (crc >> 4) & 0x0fff
+		crc = crc ^ tmp ^ fitCRCTable[b&0xf]
+
+		tmp = fitCRCTable[crc&0xf]
+		crc = (crc >> 4) & 0x0fff
+		crc = crc ^ tmp ^ fitCRCTable[(b>>4)&0xf]
+	}
+
+	return crc
+}
+
+// EncodeFIT writes the first route as a FIT course file. Turns are written
+// as course points for devices to give turn cues.
+func encodeFIT(w io.Writer, out output) error {
+
+	if len(out.Routes) == 0 {
+		return fmt.Errorf("no route to encode")
+	}
+
+	route := out.Routes[0]
+	distances := route.Distances()
+	first, last := route.Path[0], route.Path[len(route.Path)-1]
+
+	start := fitTime(out.Start)
+	end := fitTime(courseTime(out.Start, route.Length))
+	duration := uint32(math.Round(route.Length / courseSpeed * 1000))
+
+	fw := &fitWriter{locals: make(map[uint16]byte)}
+
+	fw.message(fitFileId,
+		fitField{0, fitEnum, uint8(6)}, // course
+		fitField{1, fitUint16, uint16(255)},
+		fitField{2, fitUint16, uint16(0)},
+		fitField{4, fitUint32, start},
+	)
+
+	fw.message(fitCourse,
+		fitField{4, fitEnum, uint8(1)}, // running
+		fitField{5, fitString, fmt.Sprintf("Route %d", out.Ranks[0]+1)},
+	)
+
+	fw.message(fitLap,
+		fitField{253, fitUint32, end},
+		fitField{2, fitUint32, start},
+		fitField{3, fitSint32, semicircles(first.Lat)},
+		fitField{4, fitSint32, semicircles(first.Lon)},
+		fitField{5, fitSint32, semicircles(last.Lat)},
+		fitField{6, fitSint32, semicircles(last.Lon)},
+		fitField{7, fitUint32, duration},
+		fitField{8, fitUint32, duration},
+		fitField{9, fitUint32, fitDistance(route.Length)},
+	)
+
+	fitTimer(fw, start, 0)
+
+	for i, node := range route.Path {
+		altitude := uint16(math.MaxUint16)
+		if out.Elevation && !math.IsNaN(node.Elevation) {
+			altitude = uint16(math.Round((node.Elevation + 500) * 5))
+		}
+
+		fw.message(fitRecord,
+			fitField{253, fitUint32, fitTime(courseTime(out.Start, distances[i]))},
+			fitField{0, fitSint32, semicircles(node.Lat)},
+			fitField{1, fitSint32, semicircles(node.Lon)},
+			fitField{5, fitUint32, fitDistance(distances[i])},
+			fitField{2, fitUint16, altitude},
+		)
+	}
+
+	fitTimer(fw, end, 4)
+
+	for i, turn := range route.TurnPoints() {
+		node := route.Path[turn.Index]
+		kind, name := uint8(fitRight), "Right"
+
+		if turn.Left() {
+			kind, name = fitLeft, "Left"
+		}
+
+		fw.message(fitCoursePoint,
+			fitField{254, fitUint16, uint16(i)},
+			fitField{1, fitUint32, fitTime(courseTime(out.Start, distances[turn.Index]))},
+			fitField{2, fitSint32, semicircles(node.Lat)},
+			fitField{3, fitSint32, semicircles(node.Lon)},
+			fitField{4, fitUint32, fitDistance(distances[turn.Index])},
+			fitField{5, fitEnum, kind},
+			fitField{6, fitString, name},
+		)
+	}
+
+	_, err := fw.WriteTo(w)
+	return err
+}
+
+// FitTimer writes a timer event, 0 starts the timer and 4 stops it.
+func fitTimer(fw *fitWriter, timestamp uint32, eventType uint8) {
+	fw.message(fitEvent,
+		fitField{253, fitUint32, timestamp},
+		fitField{0, fitEnum, uint8(0)},
+		fitField{1, fitEnum, eventType},
+	)
+}
+
+func fitTime(t time.Time) uint32 {
+	return uint32(t.Unix() - fitEpoch)
+}
+
+// FitDistance returns the distance in centimeters.
+func fitDistance(distance float64) uint32 {
+	return uint32(math.Round(distance * 100))
+}
+
+// Semicircles converts degrees to the units of FIT positions.
+func semicircles(degrees float64) int32 {
+	return int32(math.Round(degrees * (1 << 31) / 180))
+}
@@ -0,0 +1,175 @@
+package routeplanner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fitMessage is a decoded data message, fields are the raw little endian
+// values by field number.
+type fitMessage struct {
+	global uint16
+	fields map[byte][]byte
+}
+
+func (m fitMessage) uint(num byte) uint64 {
+	v := make([]byte, 8)
+	copy(v, m.fields[num])
+	return binary.LittleEndian.Uint64(v)
+}
+
+// decodeFIT checks the header and checksums of a FIT file and returns its
+// data messages. Only what fitWriter writes is supported.
+func decodeFIT(file []byte) ([]fitMessage, error) {
+
+	if len(file) < 16 || file[0] != 14 || string(file[8:12]) != ".FIT" {
+		return nil, errors.New("bad header")
+	}
+
+	if fitCRC(0, file[:14]) != 0 || fitCRC(0, file) != 0 {
+		return nil, errors.New("bad checksum")
+	}
+
+	data := file[14 : len(file)-2]
+	if binary.LittleEndian.Uint32(file[4:8]) != uint32(len(data)) {
+		return nil, errors.New("bad data size")
+	}
+
+	type field struct{ num, size byte }
+	type definition struct {
+		global uint16
+		fields []field
+	}
+
+	definitions := make(map[byte]definition)
+	messages := []fitMessage{}
+
+	for i := 0; i < len(data); {
+		header := data[i]
+		local := header & 0x0f
+		i++
+
+		if header&0x40 != 0 {
+			d := definition{global: binary.LittleEndian.Uint16(data[i+2:])}
+			n := int(data[i+4])
+			i += 5
+
+			for j := 0; j < n; j++ {
+				d.fields = append(d.fields, field{data[i], data[i+1]})
+				i += 3
+			}
+
+			definitions[local] = d
+			continue
+		}
+
+		d, ok := definitions[local]
+		if !ok {
+			return nil, errors.New("undefined local message type")
+		}
+
+		m := fitMessage{d.global, make(map[byte][]byte)}
+		for _, f := range d.fields {
+			m.fields[f.num] = data[i : i+int(f.size)]
+			i += int(f.size)
+		}
+
+		messages = append(messages, m)
+	}
+
+	return messages, nil
+}
+
+func TestFitCRC(t *testing.T) {
+
+	if got := fitCRC(0, []byte("123456789")); got != 0xbb3d {
+		t.Errorf("fitCRC(123456789) == %#x, want 0xbb3d", got)
+	}
+}
+
+func TestEncodeFIT(t *testing.T) {
+
+	var b bytes.Buffer
+
+	start := time.Date(2021, 6, 1, 8, 0, 0, 0, time.UTC)
+	out := output{Routes: testRoutes()[:1], Ranks: []int{2}, Elevation: true, Start: start}
+
+	if err := encodeFIT(&b, out); err != nil {
+		t.Fatalf("encodeFIT() returned %v", err)
+	}
+
+	messages, err := decodeFIT(b.Bytes())
+	if err != nil {
+		t.Fatalf("encodeFIT() output is not a valid FIT file: %v", err)
+	}
+
+	byGlobal := make(map[uint16][]fitMessage)
+	for _, m := range messages {
+		byGlobal[m.global] = append(byGlobal[m.global], m)
+	}
+
+	route := out.Routes[0]
+
+	if messages[0].global != fitFileId || messages[0].uint(0) != 6 {
+		t.Errorf("first message == %+v, want a course file id", messages[0])
+	}
+
+	if name := byGlobal[fitCourse][0].fields[5]; string(bytes.TrimRight(name, "\x00")) != "Route 3" {
+		t.Errorf("course name == %q, want Route 3", name)
+	}
+
+	if got := byGlobal[fitLap][0].uint(9); got != uint64(fitDistance(route.Length)) {
+		t.Errorf("lap distance == %v, want %v", got, fitDistance(route.Length))
+	}
+
+	if len(byGlobal[fitEvent]) != 2 || byGlobal[fitEvent][1].uint(1) != 4 {
+		t.Errorf("events == %+v, want a timer start and stop", byGlobal[fitEvent])
+	}
+
+	records := byGlobal[fitRecord]
+
+	if len(records) != len(route.Path) {
+		t.Fatalf("%d records, want %d", len(records), len(route.Path))
+	}
+
+	if got := int32(records[1].uint(0)); got != semicircles(51.271) {
+		t.Errorf("record latitude == %v, want %v", got, semicircles(51.271))
+	}
+
+	if records[0].uint(2) != 3000 || records[2].uint(2) != 0xffff {
+		t.Errorf("record altitudes == %v ... %v, want 3000 ... invalid", records[0].uint(2), records[2].uint(2))
+	}
+
+	if got := records[2].uint(253) - records[0].uint(253); got != 80 {
+		t.Errorf("route takes %vs, want 80s", got)
+	}
+
+	// the route heads north and turns right
+	points := byGlobal[fitCoursePoint]
+
+	if len(points) != 1 || points[0].uint(5) != fitRight || points[0].uint(2) != records[1].uint(0) {
+		t.Errorf("course points == %+v, want a right turn at the second record", points)
+	}
+}
+
+func TestSemicircles(t *testing.T) {
+
+	cases := []struct {
+		in   float64
+		want int32
+	}{
+		{0, 0},
+		{90, 1 << 30},
+		{-90, -1 << 30},
+		{51.27, 611674926},
+	}
+
+	for _, c := range cases {
+		if got := semicircles(c.in); got != c.want {
+			t.Errorf("semicircles(%v) == %v, want %v", c.in, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package routeplanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/yurachistic1/routeplanner-backend/routing"
+)
+
+// Output is what the response formats encode.
+type output struct {
+	Routes routing.Routes
+
+	// Ranks holds the position of every route among all the suggestions,
+	// which differs from its index when a single route is requested.
+	Ranks []int
+
+	// Explain is set when scores are requested, Directions when directions
+	// are and Elevation when nodes of the routes have elevations.
+	Explain    bool
+	Directions bool
+	Elevation  bool
+
+	// Precision of encoded polylines.
+	Precision int
+
+	// Start is the time at which courses start.
+	Start time.Time
+
+	// Locale of the texts of directions.
+	Locale *locale
+}
+
+// Speed in m/s at which courses are expected to be followed, an easy running
+// pace. Points of courses need times, which devices use for virtual partners.
+const courseSpeed = 10 / 3.6
+
+// CourseTime returns the time at which a course starting at start reaches
+// the distance.
+func courseTime(start time.Time, distance float64) time.Time {
+	return start.Add(time.Duration(distance / courseSpeed * float64(time.Second)))
+}
+
+// Format is a way of encoding routes in the response.
+type format struct {
+	ContentType string
+
+	// Extension of the file name, formats with an extension are sent as
+	// attachments to be downloaded.
+	Extension string
+
+	// Single is set for formats that hold one route, the best one is sent
+	// unless another is requested.
+	Single bool
+
+	Encode func(w io.Writer, out output) error
+}
+
+// Formats selectable by name, JSON is the default.
+var formats = map[string]format{
+	"json":     {"application/json; charset=UTF-8", "", false, encodeJSON},
+	"gpx":      {"application/gpx+xml", "gpx", false, encodeGPX},
+	"geojson":  {"application/geo+json", "", false, encodeGeoJSON},
+	"polyline": {"application/json; charset=UTF-8", "", false, encodePolylineFormat},
+	"tcx":      {"application/vnd.garmin.tcx+xml", "tcx", false, encodeTCX},
+	"fit":      {"application/vnd.ant.fit", "fit", true, encodeFIT},
+}
+
+// Respond writes the routes in the requested format. If a single route is
+// requested only that one is written.
+func respond(w http.ResponseWriter, req Request, routes routing.Routes, elevation bool) {
+
+	f := formats[req.Format]
+	if req.Format == "" {
+		f = formats["json"]
+	}
+
+	out := output{
+		Explain:    req.Explain,
+		Directions: req.Directions,
+		Elevation:  elevation,
+		Precision:  req.Precision,
+		Start:      time.Now().Truncate(time.Second),
+		Locale:     negotiateLocale(req.Lang, req.AcceptLanguage),
+	}
+	if out.Precision == 0 {
+		out.Precision = defaultPrecision
+	}
+	name := "routes"
+
+	index := req.Index
+	if index == nil && f.Single {
+		index = new(int)
+	}
+
+	if index != nil {
+		i := *index
+
+		if i < 0 || i >= len(routes) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprintf(w, "Error: route %d does not exist, there are %d", i, len(routes))
+			return
+		}
+
+		out.Routes, out.Ranks = routes[i:i+1], []int{i}
+		name = fmt.Sprintf("route-%d", i+1)
+	} else {
+		out.Routes, out.Ranks = routes, make([]int, len(routes))
+		for i := range routes {
+			out.Ranks[i] = i
+		}
+	}
+
+	w.Header().Set("Content-Type", f.ContentType)
+
+	if out.Directions {
+		w.Header().Set("Content-Language", out.Locale.Tag)
+		w.Header().Set("Vary", "Accept-Language")
+	}
+
+	if f.Extension != "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+"."+f.Extension))
+		w.Header().Set("Access-Control-Expose-Headers", "Content-Disposition")
+	}
+
+	w.WriteHeader(http.StatusOK)
+	f.Encode(w, out)
+}
+
+// EncodeJSON writes the routes as a Responce.
+func encodeJSON(w io.Writer, out output) error {
+	response := routesToResponce(out)
+	return json.NewEncoder(w).Encode(&response)
+}
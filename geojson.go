@@ -0,0 +1,86 @@
+package routeplanner
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+
+	"github.com/yurachistic1/routeplanner-backend/routing"
+)
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Type       string            `json:"type"`
+	Geometry   lineString        `json:"geometry"`
+	Properties featureProperties `json:"properties"`
+}
+
+type lineString struct {
+	Type        string      `json:"type"`
+	Coordinates [][]float64 `json:"coordinates"`
+}
+
+type featureProperties struct {
+	Distance     float64            `json:"distance"`
+	Rank         int                `json:"rank"`
+	Turns        int                `json:"turns"`
+	RepeatVisits int                `json:"repeatVisits"`
+	Score        float64            `json:"score"`
+	Breakdown    map[string]float64 `json:"breakdown,omitempty"`
+}
+
+// EncodeGeoJSON writes the routes as a GeoJSON FeatureCollection of
+// LineStrings. Positions are [lon, lat] and include the elevation as a third
+// value if it is known for every node of the route.
+func encodeGeoJSON(w io.Writer, out output) error {
+
+	collection := featureCollection{Type: "FeatureCollection", Features: []feature{}}
+
+	for i, route := range out.Routes {
+		f := feature{
+			Type:     "Feature",
+			Geometry: lineString{Type: "LineString", Coordinates: coordinates(route, out.Elevation)},
+			Properties: featureProperties{
+				Distance:     route.Length,
+				Rank:         out.Ranks[i] + 1,
+				Turns:        route.Turns,
+				RepeatVisits: route.RepeatVisits,
+				Score:        route.Score,
+			},
+		}
+
+		if out.Explain {
+			f.Properties.Breakdown = route.Breakdown
+		}
+
+		collection.Features = append(collection.Features, f)
+	}
+
+	return json.NewEncoder(w).Encode(&collection)
+}
+
+// Coordinates returns the GeoJSON positions of the nodes of the route.
+func coordinates(route routing.Route, elevation bool) [][]float64 {
+
+	for _, node := range route.Path {
+		if math.IsNaN(node.Elevation) {
+			elevation = false
+		}
+	}
+
+	positions := make([][]float64, len(route.Path))
+
+	for i, node := range route.Path {
+		if elevation {
+			positions[i] = []float64{node.Lon, node.Lat, node.Elevation}
+		} else {
+			positions[i] = []float64{node.Lon, node.Lat}
+		}
+	}
+
+	return positions
+}
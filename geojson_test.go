@@ -0,0 +1,94 @@
+package routeplanner
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeGeoJSON(t *testing.T) {
+
+	cases := []struct {
+		out        output
+		first      []float64
+		last       []float64
+		breakdown  bool
+		secondRank int
+	}{
+		{output{Routes: testRoutes(), Ranks: []int{0, 1}}, []float64{0.19, 51.27}, []float64{0.1916, 51.271}, false, 2},
+		{output{Routes: testRoutes()[1:], Ranks: []int{1}, Explain: true}, []float64{0.19, 51.271}, []float64{0.1916, 51.271}, true, 0},
+	}
+
+	for _, c := range cases {
+		var b bytes.Buffer
+
+		if err := encodeGeoJSON(&b, c.out); err != nil {
+			t.Fatalf("encodeGeoJSON() returned %v", err)
+		}
+
+		var doc featureCollection
+
+		if err := json.Unmarshal(b.Bytes(), &doc); err != nil {
+			t.Fatalf("encodeGeoJSON() output is not valid JSON: %v", err)
+		}
+
+		if doc.Type != "FeatureCollection" || len(doc.Features) != len(c.out.Routes) {
+			t.Fatalf("encodeGeoJSON() == %s", b.String())
+		}
+
+		f := doc.Features[0]
+		coords := f.Geometry.Coordinates
+
+		if f.Type != "Feature" || f.Geometry.Type != "LineString" {
+			t.Errorf("feature types == %q, %q", f.Type, f.Geometry.Type)
+		}
+
+		if !reflect.DeepEqual(coords[0], c.first) || !reflect.DeepEqual(coords[len(coords)-1], c.last) {
+			t.Errorf("coordinates == %v, want %v ... %v", coords, c.first, c.last)
+		}
+
+		route := c.out.Routes[0]
+		want := featureProperties{
+			Distance:     route.Length,
+			Rank:         c.out.Ranks[0] + 1,
+			Turns:        route.Turns,
+			RepeatVisits: route.RepeatVisits,
+			Score:        route.Score,
+		}
+
+		if (f.Properties.Breakdown != nil) != c.breakdown {
+			t.Errorf("breakdown == %v, want one %v", f.Properties.Breakdown, c.breakdown)
+		}
+
+		f.Properties.Breakdown = nil
+		if !reflect.DeepEqual(f.Properties, want) {
+			t.Errorf("properties == %+v, want %+v", f.Properties, want)
+		}
+
+		if c.secondRank > 0 && doc.Features[1].Properties.Rank != c.secondRank {
+			t.Errorf("second rank == %d, want %d", doc.Features[1].Properties.Rank, c.secondRank)
+		}
+	}
+}
+
+func TestCoordinates(t *testing.T) {
+
+	routes := testRoutes()
+
+	// the last node of both routes has no elevation
+	if got := coordinates(routes[0], true); len(got[0]) != 2 {
+		t.Errorf("coordinates() == %v, want no elevations", got)
+	}
+
+	routes[0].Path = routes[0].Path[:2]
+
+	want := [][]float64{{0.19, 51.27, 100}, {0.19, 51.271, 110}}
+	if got := coordinates(routes[0], true); !reflect.DeepEqual(got, want) {
+		t.Errorf("coordinates() == %v, want %v", got, want)
+	}
+
+	if got := coordinates(routes[0], false); len(got[0]) != 2 {
+		t.Errorf("coordinates() == %v, want no elevations", got)
+	}
+}
@@ -0,0 +1,86 @@
+package routeplanner
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Gpx is the root element of a GPX 1.1 document.
+type gpx struct {
+	XMLName  xml.Name    `xml:"http://www.topografix.com/GPX/1/1 gpx"`
+	Version  string      `xml:"version,attr"`
+	Creator  string      `xml:"creator,attr"`
+	Metadata gpxMetadata `xml:"metadata"`
+	Tracks   []gpxTrack  `xml:"trk"`
+}
+
+type gpxMetadata struct {
+	Name string `xml:"name"`
+	Desc string `xml:"desc,omitempty"`
+}
+
+type gpxTrack struct {
+	Name     string       `xml:"name"`
+	Desc     string       `xml:"desc,omitempty"`
+	Number   int          `xml:"number"`
+	Segments []gpxSegment `xml:"trkseg"`
+}
+
+type gpxSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+type gpxPoint struct {
+	Lat float64  `xml:"lat,attr"`
+	Lon float64  `xml:"lon,attr"`
+	Ele *float64 `xml:"ele,omitempty"`
+}
+
+// EncodeGPX writes the routes as tracks of a GPX 1.1 document. The distance
+// and, if requested, the score of every route go into its description and
+// elevations into its points when they are known.
+func encodeGPX(w io.Writer, out output) error {
+
+	doc := gpx{
+		Version:  "1.1",
+		Creator:  "routeplanner",
+		Metadata: gpxMetadata{Name: "Suggested routes"},
+	}
+
+	for i, route := range out.Routes {
+		track := gpxTrack{
+			Name:   fmt.Sprintf("Route %d", out.Ranks[i]+1),
+			Desc:   fmt.Sprintf("Distance: %.0f m", route.Length),
+			Number: out.Ranks[i] + 1,
+		}
+
+		if out.Explain {
+			track.Desc += fmt.Sprintf(", score: %.1f", route.Score)
+		}
+
+		segment := gpxSegment{Points: make([]gpxPoint, len(route.Path))}
+
+		for j, node := range route.Path {
+			segment.Points[j] = gpxPoint{Lat: node.Lat, Lon: node.Lon}
+
+			if out.Elevation && !math.IsNaN(node.Elevation) {
+				ele := node.Elevation
+				segment.Points[j].Ele = &ele
+			}
+		}
+
+		track.Segments = []gpxSegment{segment}
+		doc.Tracks = append(doc.Tracks, track)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	return encoder.Encode(doc)
+}
@@ -0,0 +1,73 @@
+package routeplanner
+
+import (
+	"bytes"
+	"encoding/xml"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/yurachistic1/routeplanner-backend/routing"
+)
+
+// testRoutes returns two short routes, the nodes of the first one have
+// elevations except for the last one.
+func testRoutes() routing.Routes {
+
+	n1 := &routing.Node{Id: 1, Lat: 51.27, Lon: 0.19, Elevation: 100, Edges: map[routing.Id]routing.Edge{}}
+	n2 := &routing.Node{Id: 2, Lat: 51.271, Lon: 0.19, Elevation: 110, Edges: map[routing.Id]routing.Edge{}}
+	n3 := &routing.Node{Id: 3, Lat: 51.271, Lon: 0.1916, Elevation: math.NaN(), Edges: map[routing.Id]routing.Edge{}}
+
+	n1.Edges[2] = routing.Edge{Distance: routing.Haversine(n1, n2), Bearing: routing.Bearing(n1, n2)}
+	n2.Edges[3] = routing.Edge{Distance: routing.Haversine(n2, n3), Bearing: routing.Bearing(n2, n3)}
+
+	return routing.Routes{
+		{Path: []*routing.Node{n1, n2, n3}, Length: n1.Edges[2].Distance + n2.Edges[3].Distance, Score: 12.5, Turns: 1},
+		{Path: []*routing.Node{n2, n3}, Length: n2.Edges[3].Distance, Score: 20, RepeatVisits: 1, Breakdown: map[string]float64{"repeats": 20}},
+	}
+}
+
+func TestEncodeGPX(t *testing.T) {
+
+	var b bytes.Buffer
+
+	out := output{Routes: testRoutes(), Ranks: []int{0, 4}, Explain: true, Elevation: true}
+
+	if err := encodeGPX(&b, out); err != nil {
+		t.Fatalf("encodeGPX() returned %v", err)
+	}
+
+	if !strings.HasPrefix(b.String(), "<?xml") || !strings.Contains(b.String(), `xmlns="http://www.topografix.com/GPX/1/1"`) {
+		t.Errorf("encodeGPX() == %s, want a GPX 1.1 document", b.String())
+	}
+
+	var doc gpx
+
+	if err := xml.Unmarshal(b.Bytes(), &doc); err != nil {
+		t.Fatalf("encodeGPX() output is not valid XML: %v", err)
+	}
+
+	if doc.Version != "1.1" || len(doc.Tracks) != 2 {
+		t.Fatalf("encodeGPX() == %+v", doc)
+	}
+
+	first, second := doc.Tracks[0], doc.Tracks[1]
+
+	if first.Name != "Route 1" || second.Name != "Route 5" || second.Number != 5 {
+		t.Errorf("track names == %q, %q", first.Name, second.Name)
+	}
+
+	if !strings.Contains(first.Desc, "score: 12.5") || !strings.Contains(first.Desc, "Distance: 223 m") {
+		t.Errorf("track description == %q", first.Desc)
+	}
+
+	points := first.Segments[0].Points
+
+	if len(points) != 3 || points[1].Lat != 51.271 || points[1].Lon != 0.19 {
+		t.Fatalf("track points == %+v", points)
+	}
+
+	if points[0].Ele == nil || *points[0].Ele != 100 || points[2].Ele != nil {
+		t.Errorf("track point elevations == %v, %v", points[0].Ele, points[2].Ele)
+	}
+}
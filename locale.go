@@ -0,0 +1,231 @@
+package routeplanner
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Language packs of directions, one JSON file per locale named by its
+// language tag. A pack can be added by dropping a file into the directory.
+//
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// Locale used when none of the requested ones is available and for messages
+// missing from other locales.
+const defaultLocale = "en"
+
+// Locale is a language pack of directions. Messages are templates keyed by
+// maneuver action, with a ".way" suffix for the variant naming the way.
+type locale struct {
+	Tag      string
+	Messages map[string]*template.Template
+
+	// Compass points starting at north and going clockwise.
+	Compass []string
+
+	// Highways describes unnamed ways by their highway tag.
+	Highways map[string]string
+
+	// Decimal separator of distances.
+	Decimal string
+}
+
+// LocalePack is the JSON form of a locale.
+type localePack struct {
+	Messages map[string]string `json:"messages"`
+	Compass  []string          `json:"compass"`
+	Highways map[string]string `json:"highways"`
+	Decimal  string            `json:"decimal"`
+}
+
+// MessageData is what message templates are executed with, distances use
+// the number alone.
+type messageData struct {
+	Direction string
+	Way       string
+	Distance  string
+}
+
+var locales = mustLoadLocales(localeFiles)
+
+// LoadLocales reads all the language packs in the locales directory of fsys
+// keyed by their lower case tag.
+func loadLocales(fsys fs.FS) (map[string]*locale, error) {
+
+	files, err := fs.Glob(fsys, "locales/*.json")
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]*locale, len(files))
+
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		var pack localePack
+		if err := json.Unmarshal(data, &pack); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+
+		if len(pack.Compass) != compassPoints {
+			return nil, fmt.Errorf("%s: %d compass points, want %d", file, len(pack.Compass), compassPoints)
+		}
+
+		loc := &locale{
+			Tag:      strings.TrimSuffix(path.Base(file), ".json"),
+			Messages: make(map[string]*template.Template, len(pack.Messages)),
+			Compass:  pack.Compass,
+			Highways: pack.Highways,
+			Decimal:  pack.Decimal,
+		}
+
+		for key, text := range pack.Messages {
+			if loc.Messages[key], err = template.New(key).Option("missingkey=error").Parse(text); err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+		}
+
+		res[strings.ToLower(loc.Tag)] = loc
+	}
+
+	if _, ok := res[defaultLocale]; !ok {
+		return nil, fmt.Errorf("no %s locale", defaultLocale)
+	}
+
+	return res, nil
+}
+
+func mustLoadLocales(fsys fs.FS) map[string]*locale {
+	res, err := loadLocales(fsys)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// Has reports whether there is a message with the key in this or the default
+// locale.
+func (loc *locale) has(key string) bool {
+	return loc.Messages[key] != nil || locales[defaultLocale].Messages[key] != nil
+}
+
+// Text returns the message with the key, it falls back to the default locale
+// if the message is missing from this one.
+func (loc *locale) text(key string, data interface{}) string {
+
+	tmpl, ok := loc.Messages[key]
+	if !ok {
+		tmpl = locales[defaultLocale].Messages[key]
+	}
+
+	if tmpl == nil {
+		return key
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return key
+	}
+
+	return b.String()
+}
+
+// NegotiateLocale returns the locale of directions. The lang parameter is
+// preferred over the Accept-Language header, both can list several language
+// tags. A locale matches a tag exactly or by its language alone, when
+// nothing matches the default locale is used.
+func negotiateLocale(lang, acceptLanguage string) *locale {
+
+	for _, header := range []string{lang, acceptLanguage} {
+		for _, tag := range languageTags(header) {
+			if loc := matchLocale(tag); loc != nil {
+				return loc
+			}
+		}
+	}
+
+	return locales[defaultLocale]
+}
+
+// MatchLocale returns the locale for a language tag or nil.
+func matchLocale(tag string) *locale {
+
+	tag = strings.ToLower(tag)
+
+	if loc, ok := locales[tag]; ok {
+		return loc
+	}
+
+	language := strings.SplitN(tag, "-", 2)[0]
+
+	// locales are in a map, the most general one is picked for stable results
+	var candidates []string
+	for key := range locales {
+		if strings.SplitN(key, "-", 2)[0] == language {
+			candidates = append(candidates, key)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	sort.Strings(candidates)
+	return locales[candidates[0]]
+}
+
+// LanguageTags returns the tags of an Accept-Language style list ordered by
+// their quality, tags with a quality of zero and wildcards are left out.
+func languageTags(list string) []string {
+
+	type weighted struct {
+		tag     string
+		quality float64
+	}
+
+	tags := []weighted{}
+
+	for _, part := range strings.Split(list, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.TrimSpace(fields[0])
+		quality := 1.0
+
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+
+		if tag == "" || tag == "*" || quality <= 0 {
+			continue
+		}
+
+		tags = append(tags, weighted{tag, quality})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].quality > tags[j].quality
+	})
+
+	res := make([]string, len(tags))
+	for i, t := range tags {
+		res[i] = t.tag
+	}
+
+	return res
+}
@@ -0,0 +1,155 @@
+package routeplanner
+
+import (
+	"io"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"text/template"
+
+	"github.com/yurachistic1/routeplanner-backend/routing"
+)
+
+func TestLocalePacks(t *testing.T) {
+
+	for _, tag := range []string{"en", "fr", "de", "ru", "zh-tw"} {
+		if _, ok := locales[tag]; !ok {
+			t.Errorf("no %s locale", tag)
+		}
+	}
+
+	data := messageData{Direction: "D", Way: "W", Distance: "1"}
+
+	for tag, loc := range locales {
+		for key := range locales[defaultLocale].Messages {
+			if loc.Messages[key] == nil {
+				t.Errorf("%s: message %q is missing", tag, key)
+			}
+		}
+
+		for key, tmpl := range loc.Messages {
+			if err := tmpl.Execute(io.Discard, data); err != nil {
+				t.Errorf("%s: message %q: %v", tag, key, err)
+			}
+		}
+
+		for highway := range locales[defaultLocale].Highways {
+			if loc.Highways[highway] == "" {
+				t.Errorf("%s: description of %s is missing", tag, highway)
+			}
+		}
+	}
+}
+
+func TestLoadLocales(t *testing.T) {
+
+	en := `{"messages": {"left": "Turn left"}, "compass": ["n", "ne", "e", "se", "s", "sw", "w", "nw"], "decimal": "."}`
+
+	cases := []struct {
+		files   fstest.MapFS
+		wantErr bool
+	}{
+		{fstest.MapFS{"locales/en.json": {Data: []byte(en)}}, false},
+		{fstest.MapFS{"locales/en.json": {Data: []byte(en)}, "locales/fr.json": {Data: []byte(`{"messages": `)}}, true},
+		{fstest.MapFS{"locales/en.json": {Data: []byte(`{"messages": {"left": "{{.Way"}, "compass": ["n", "ne", "e", "se", "s", "sw", "w", "nw"]}`)}}, true},
+		{fstest.MapFS{"locales/en.json": {Data: []byte(`{"compass": ["n", "s"]}`)}}, true},
+		{fstest.MapFS{"locales/fr.json": {Data: []byte(en)}}, true},
+	}
+
+	for i, c := range cases {
+		got, err := loadLocales(c.files)
+
+		if (err != nil) != c.wantErr {
+			t.Errorf("case %d: loadLocales() returned %v, want an error %v", i, err, c.wantErr)
+		}
+
+		if err == nil && got["en"].Tag != "en" {
+			t.Errorf("case %d: loadLocales() == %v", i, got)
+		}
+	}
+}
+
+func TestLanguageTags(t *testing.T) {
+
+	cases := []struct {
+		in   string
+		want []string
+	}{
+		{"", []string{}},
+		{"fr", []string{"fr"}},
+		{"de-CH, fr;q=0.9, en;q=0.8, *;q=0.5", []string{"de-CH", "fr", "en"}},
+		{"en;q=0.2, ru, zh-TW;q=0.7", []string{"ru", "zh-TW", "en"}},
+		{"fr;q=0, de", []string{"de"}},
+	}
+
+	for _, c := range cases {
+		if got := languageTags(c.in); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("languageTags(%q) == %v, want %v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestNegotiateLocale(t *testing.T) {
+
+	cases := []struct {
+		lang, acceptLanguage string
+		want                 string
+	}{
+		{"", "", "en"},
+		{"fr", "", "fr"},
+		{"FR-ca", "", "fr"},
+		{"de", "ru", "de"},
+		{"xx", "ru", "ru"},
+		{"", "ja, zh-Hant-TW;q=0.8, en;q=0.5", "zh-TW"},
+		{"", "it, es", "en"},
+	}
+
+	for _, c := range cases {
+		if got := negotiateLocale(c.lang, c.acceptLanguage); got.Tag != c.want {
+			t.Errorf("negotiateLocale(%q, %q) == %s, want %s", c.lang, c.acceptLanguage, got.Tag, c.want)
+		}
+	}
+}
+
+func TestLocalisedInstruction(t *testing.T) {
+
+	knole := &routing.Way{Highway: "footway", Name: "Knole Path"}
+	steps := &routing.Way{Highway: "steps"}
+	road := &routing.Way{Highway: "residential"}
+
+	cases := []struct {
+		tag  string
+		m    routing.Maneuver
+		want string
+	}{
+		{"fr", routing.Maneuver{Action: routing.Left, Way: knole}, "Tournez à gauche sur Knole Path"},
+		{"fr", routing.Maneuver{Action: routing.Depart, Way: road, Bearing: 90}, "Dirigez-vous vers l'est"},
+		{"fr", routing.Maneuver{Action: routing.Continue, Way: road, Distance: 1460}, "Continuez sur 1,5 km"},
+		{"de", routing.Maneuver{Action: routing.Right, Way: steps}, "Biegen Sie rechts auf die Treppe ab"},
+		{"de", routing.Maneuver{Action: routing.UTurn, Way: knole}, "Wenden Sie"},
+		{"ru", routing.Maneuver{Action: routing.SharpLeft, Way: road}, "Резко поверните налево"},
+		{"ru", routing.Maneuver{Action: routing.Continue, Way: road, Distance: 400}, "Продолжайте движение 400 м"},
+		{"zh-tw", routing.Maneuver{Action: routing.Left, Way: knole}, "左轉進入Knole Path"},
+	}
+
+	for _, c := range cases {
+		if got := instruction(c.m, false, locales[c.tag]); got != c.want {
+			t.Errorf("%s: instruction(%v) == %q, want %q", c.tag, c.m.Action, got, c.want)
+		}
+	}
+}
+
+func TestLocaleFallback(t *testing.T) {
+
+	// a pack missing a message falls back to English for it
+	partial := &locale{Tag: "xx", Compass: locales["fr"].Compass}
+	partial.Messages = map[string]*template.Template{"left": locales["fr"].Messages["left"]}
+
+	if got := instruction(routing.Maneuver{Action: routing.Left}, false, partial); got != "Tournez à gauche" {
+		t.Errorf("instruction(left) == %q", got)
+	}
+
+	if got := instruction(routing.Maneuver{Action: routing.Arrive}, true, partial); got != "Arrive back at the start" {
+		t.Errorf("instruction(arrive) == %q", got)
+	}
+}
@@ -9,51 +9,113 @@ import (
 // edges between them without dead ends.
 func OSMToGraph(res overpass.Response) (graph routing.Graph) {
 
-	graph = make(routing.Graph)
-	elementsGrouped := struct {
-		Nodes []overpass.Element
-		Ways  []overpass.Element
-	}{[]overpass.Element{}, []overpass.Element{}}
-
-	// group elements
-	for _, element := range res.Elements {
-		switch element.Type {
-		case "way":
-			elementsGrouped.Ways = append(elementsGrouped.Ways, element)
-		case "node":
-			elementsGrouped.Nodes = append(elementsGrouped.Nodes, element)
-		}
+	builder := NewGraphBuilder()
+
+	for i := range res.Elements {
+		builder.Add(&res.Elements[i])
+	}
+
+	return builder.Graph()
+}
+
+// GraphBuilder assembles a graph from elements one at a time so that they can
+// be added while a response is still being decoded. Overpass lists ways
+// before their nodes, so only the node ids and interned attributes of ways are
+// kept until all the elements have been added.
+type GraphBuilder struct {
+	graph routing.Graph
+	ways  []pendingWay
+	attrs routing.Ways
+}
+
+type pendingWay struct {
+	id    int
+	nodes []routing.Id
+	attrs *routing.Way
+}
+
+func NewGraphBuilder() *GraphBuilder {
+	return &GraphBuilder{
+		graph: make(routing.Graph),
+		attrs: make(routing.Ways),
 	}
+}
+
+// Add inserts a node into the graph or records a way to be connected later.
+// It has the signature of the callback of overpass.DecodeElements.
+func (b *GraphBuilder) Add(element *overpass.Element) error {
 
-	// insert all the nodes into the Graph
-	for _, node := range elementsGrouped.Nodes {
-		graph[routing.Id(node.Id)] =
+	switch element.Type {
+	case "node":
+		b.graph[routing.Id(element.Id)] =
 			&routing.Node{
-				routing.Id(node.Id),
-				node.Lat, node.Lon,
-				[]routing.Id{},
-				make(map[routing.Id]routing.Edge)}
+				Id:       routing.Id(element.Id),
+				Lat:      element.Lat,
+				Lon:      element.Lon,
+				Adjacent: []routing.Id{},
+				Edges:    make(map[routing.Id]routing.Edge)}
+	case "way":
+		if len(element.Nodes) > 1 {
+			way := make([]routing.Id, len(element.Nodes))
+			for i, id := range element.Nodes {
+				way[i] = routing.Id(id)
+			}
+			b.ways = append(b.ways, pendingWay{element.Id, way, b.attrs.Intern(wayAttributes(element.Tags))})
+		}
 	}
 
-	// connect all the nodes
-	for _, way := range elementsGrouped.Ways {
-		if len(way.Nodes) > 1 {
-			for i := 0; i < len(way.Nodes)-1; i += 1 {
-				var n1 *routing.Node = graph[routing.Id(way.Nodes[i])]
-				var n2 *routing.Node = graph[routing.Id(way.Nodes[i+1])]
+	return nil
+}
+
+// Graph connects all the nodes along the recorded ways and returns the graph
+// without dead ends.
+func (b *GraphBuilder) Graph() routing.Graph {
+
+	graph := b.graph
+
+	for _, way := range b.ways {
+		for i := 0; i < len(way.nodes)-1; i += 1 {
+			var n1 *routing.Node = graph[way.nodes[i]]
+			var n2 *routing.Node = graph[way.nodes[i+1]]
 
-				graph[n1.Id].Adjacent = append(graph[n1.Id].Adjacent, n2.Id)
-				graph[n1.Id].Edges[n2.Id] =
-					routing.Edge{routing.Haversine(n1, n2), routing.Bearing(n1, n2)}
+			if n1 == nil || n2 == nil {
+				continue
+			}
 
-				graph[n2.Id].Adjacent = append(graph[n2.Id].Adjacent, n1.Id)
-				graph[n2.Id].Edges[n1.Id] =
-					routing.Edge{routing.Haversine(n1, n2), routing.Bearing(n2, n1)}
+			graph[n1.Id].Adjacent = append(graph[n1.Id].Adjacent, n2.Id)
+			graph[n1.Id].Edges[n2.Id] = routing.Edge{
+				Distance: routing.Haversine(n1, n2),
+				Bearing:  routing.Bearing(n1, n2),
+				Way:      way.attrs,
+				WayId:    way.id,
+			}
 
+			graph[n2.Id].Adjacent = append(graph[n2.Id].Adjacent, n1.Id)
+			graph[n2.Id].Edges[n1.Id] = routing.Edge{
+				Distance: routing.Haversine(n1, n2),
+				Bearing:  routing.Bearing(n2, n1),
+				Way:      way.attrs,
+				WayId:    way.id,
 			}
 		}
 	}
 
+	b.ways = nil
+
 	graph.RemoveDeadEnds()
 	return graph
 }
+
+// WayAttributes picks the tags of a way that are kept on the edges.
+func wayAttributes(tags map[string]string) routing.Way {
+	return routing.Way{
+		Highway:    tags["highway"],
+		Surface:    tags["surface"],
+		Smoothness: tags["smoothness"],
+		Lit:        tags["lit"],
+		Sidewalk:   tags["sidewalk"],
+		Name:       tags["name"],
+		Access:     tags["access"],
+		Incline:    tags["incline"],
+	}
+}
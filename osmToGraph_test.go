@@ -0,0 +1,51 @@
+package routeplanner
+
+import (
+	"testing"
+
+	"github.com/yurachistic1/routeplanner-backend/overpass"
+	"github.com/yurachistic1/routeplanner-backend/routing"
+)
+
+func TestOSMToGraph(t *testing.T) {
+
+	tags := map[string]string{"highway": "footway", "surface": "gravel", "name": "Knole Path", "width": "2"}
+
+	// a triangle with a dead end and a way referencing a missing node
+	res := overpass.Response{Elements: []overpass.Element{
+		{Type: "way", Id: 10, Nodes: []int{1, 2}, Tags: tags},
+		{Type: "way", Id: 11, Nodes: []int{2, 3, 1}, Tags: tags},
+		{Type: "way", Id: 12, Nodes: []int{3, 4}, Tags: map[string]string{"highway": "steps"}},
+		{Type: "way", Id: 13, Nodes: []int{3, 5}, Tags: tags},
+		{Type: "node", Id: 1, Lat: 51.5, Lon: -0.1},
+		{Type: "node", Id: 2, Lat: 51.501, Lon: -0.1},
+		{Type: "node", Id: 3, Lat: 51.501, Lon: -0.101},
+		{Type: "node", Id: 4, Lat: 51.502, Lon: -0.101},
+	}}
+
+	graph := OSMToGraph(res)
+
+	if len(graph) != 3 {
+		t.Fatalf("OSMToGraph() == %v, want 3 nodes", graph)
+	}
+
+	want := routing.Way{Highway: "footway", Surface: "gravel", Name: "Knole Path"}
+
+	e1, e2 := graph[1].Edges[2], graph[2].Edges[3]
+
+	if e1.Way == nil || *e1.Way != want {
+		t.Errorf("edge 1-2 way == %+v, want %+v", e1.Way, want)
+	}
+
+	if e1.Way != e2.Way {
+		t.Errorf("edges of ways with the same tags do not share attributes")
+	}
+
+	if e1.WayId != 10 || e2.WayId != 11 {
+		t.Errorf("edge way ids == %v, %v, want 10, 11", e1.WayId, e2.WayId)
+	}
+
+	if e1.Distance < 111 || e1.Distance > 112 {
+		t.Errorf("edge 1-2 distance == %v, want about 111m", e1.Distance)
+	}
+}
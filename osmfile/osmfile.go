@@ -0,0 +1,234 @@
+// Package osmfile reads local OSM extracts in XML or PBF format and turns them
+// into the same pedestrian data an overpass query would return, so that the
+// planner can run without access to a public overpass api instance.
+package osmfile
+
+import (
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/yurachistic1/routeplanner-backend/overpass"
+)
+
+// Read returns ways selected by filter that have at least one node within
+// the area together with all of their nodes. The format is picked by the
+// extension of the file, .osm.pbf or .pbf for PBF and anything else for XML.
+// The file is read twice, first for the ways and then for the nodes they
+// reference, so that only those nodes are held in memory.
+func Read(path string, area overpass.Area, filter overpass.Filter) (overpass.Response, error) {
+
+	x, err := Load(path, filter)
+
+	if err != nil {
+		return overpass.Response{}, err
+	}
+
+	return x.Response(area), nil
+}
+
+// Extract holds the ways of a file selected by a filter and their nodes, so
+// that data for many areas can be taken from it without reading it again. It
+// is safe for concurrent use.
+type Extract struct {
+	e *extract
+}
+
+// Load reads the ways selected by filter from a file like Read does, but
+// leaves picking those within an area to Response.
+func Load(path string, filter overpass.Filter) (*Extract, error) {
+
+	f, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	read := readXML
+
+	if strings.HasSuffix(path, ".pbf") {
+		read = readPBF
+	}
+
+	e, err := readTwice(f, filter, read)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Extract{e}, nil
+}
+
+// Response returns the ways that have at least one node within the area
+// together with all of their nodes.
+func (x *Extract) Response(area overpass.Area) overpass.Response {
+	return x.e.response(area)
+}
+
+// ReadTwice reads the ways and then the nodes with read, see Read.
+func readTwice(r io.ReadSeeker, filter overpass.Filter, read func(io.Reader, *extract) error) (*extract, error) {
+
+	e := newExtract(filter)
+
+	if err := read(r, e); err != nil {
+		return nil, err
+	}
+
+	e.nodePass()
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	if err := read(r, e); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+type node struct {
+	lat float64
+	lon float64
+}
+
+type way struct {
+	id   int
+	tags map[string]string
+	refs []int
+}
+
+// Extract accumulates elements while a file is being decoded. The first pass
+// keeps the ways that match the filter, the second one only the nodes they
+// reference and which of those nodes are part of a building.
+type extract struct {
+	filter    overpass.Filter
+	nodes     map[int]node
+	ways      []way
+	buildings map[int]bool
+
+	// referenced is set once the ways have been read
+	referenced map[int]bool
+}
+
+func newExtract(filter overpass.Filter) *extract {
+	return &extract{
+		filter:    filter,
+		nodes:     make(map[int]node),
+		buildings: make(map[int]bool),
+	}
+}
+
+// NodePass prepares the second pass over the file.
+func (e *extract) nodePass() {
+
+	e.referenced = make(map[int]bool)
+
+	for _, w := range e.ways {
+		for _, ref := range w.refs {
+			e.referenced[ref] = true
+		}
+	}
+}
+
+func (e *extract) addNode(id int, lat, lon float64) {
+	if e.referenced[id] {
+		e.nodes[id] = node{lat, lon}
+	}
+}
+
+func (e *extract) addWay(id int, tags map[string]string, refs []int) {
+
+	if e.referenced == nil {
+		if e.filter.Match(tags) {
+			e.ways = append(e.ways, way{id, tags, refs})
+		}
+		return
+	}
+
+	if building, ok := tags["building"]; ok && building != "no" {
+		for _, ref := range refs {
+			if e.referenced[ref] {
+				e.buildings[ref] = true
+			}
+		}
+	}
+}
+
+// Response selects the ways within the area and returns them in the same order
+// as the overpass query: ways first followed by all of the referenced nodes.
+// Nodes missing from the file, e.g. cut off at the edge of an extract, are
+// left out and so are the segments of the ways leading to them.
+func (e *extract) response(area overpass.Area) (res overpass.Response) {
+
+	res.Version = 0.6
+	res.Generator = "routeplanner osmfile"
+	res.Elements = []overpass.Element{}
+
+	referenced := make(map[int]bool)
+
+	for _, w := range e.ways {
+		if !e.within(w, area) || e.nearBuilding(w) {
+			continue
+		}
+
+		for _, ref := range w.refs {
+			if _, ok := e.nodes[ref]; ok {
+				referenced[ref] = true
+			}
+		}
+
+		res.Elements = append(res.Elements, overpass.Element{
+			Type:  "way",
+			Id:    w.id,
+			Tags:  w.tags,
+			Nodes: w.refs,
+		})
+	}
+
+	ids := make([]int, 0, len(referenced))
+	for id := range referenced {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		n := e.nodes[id]
+		res.Elements = append(res.Elements, overpass.Element{
+			Type: "node",
+			Id:   id,
+			Lat:  n.lat,
+			Lon:  n.lon,
+		})
+	}
+
+	return res
+}
+
+// Within reports whether any node of the way lies within the area.
+func (e *extract) within(w way, area overpass.Area) bool {
+	for _, ref := range w.refs {
+		n, ok := e.nodes[ref]
+		if ok && area.Contains(n.lat, n.lon) {
+			return true
+		}
+	}
+	return false
+}
+
+// NearBuilding reports whether the way is a highway that shares a node with a
+// building, such ways usually run inside or along the walls of a building.
+func (e *extract) nearBuilding(w way) bool {
+	if _, ok := w.tags["highway"]; !ok || !e.filter.ExcludeNearBuildings {
+		return false
+	}
+	for _, ref := range w.refs {
+		if e.buildings[ref] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package osmfile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yurachistic1/routeplanner-backend/overpass"
+)
+
+const testXML = `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6">
+  <node id="1" lat="51.500" lon="-0.100"/>
+  <node id="2" lat="51.501" lon="-0.100"/>
+  <node id="3" lat="51.502" lon="-0.100"/>
+  <node id="4" lat="51.600" lon="-0.100"/>
+  <node id="5" lat="51.501" lon="-0.101"/>
+  <node id="6" lat="51.502" lon="-0.101"/>
+  <way id="10">
+    <nd ref="1"/><nd ref="2"/><nd ref="3"/>
+    <tag k="highway" v="footway"/>
+  </way>
+  <way id="11">
+    <nd ref="3"/><nd ref="4"/>
+    <tag k="highway" v="residential"/>
+    <tag k="access" v="private"/>
+  </way>
+  <way id="12">
+    <nd ref="2"/><nd ref="5"/>
+    <tag k="highway" v="motorway"/>
+  </way>
+  <way id="13">
+    <nd ref="5"/><nd ref="6"/>
+    <tag k="highway" v="path"/>
+  </way>
+  <way id="14">
+    <nd ref="6"/><nd ref="4"/><nd ref="6"/>
+    <tag k="building" v="yes"/>
+  </way>
+</osm>`
+
+func TestReadXML(t *testing.T) {
+
+	bounds := overpass.Bounds{South: 51.49, West: -0.11, North: 51.51, East: -0.09}
+
+	res, err := ReadXML(strings.NewReader(testXML), bounds, overpass.Pedestrian)
+
+	if err != nil {
+		t.Fatalf("ReadXML() returned error %v", err)
+	}
+
+	var ways, nodes []int
+
+	for _, e := range res.Elements {
+		switch e.Type {
+		case "way":
+			ways = append(ways, e.Id)
+		case "node":
+			nodes = append(nodes, e.Id)
+		}
+	}
+
+	if len(ways) != 1 || ways[0] != 10 {
+		t.Errorf("ReadXML() ways == %v, want [10]", ways)
+	}
+
+	if len(nodes) != 3 || nodes[0] != 1 || nodes[2] != 3 {
+		t.Errorf("ReadXML() nodes == %v, want [1 2 3]", nodes)
+	}
+
+	if res.Elements[0].Tags["highway"] != "footway" {
+		t.Errorf("ReadXML() way tags == %v, want highway=footway", res.Elements[0].Tags)
+	}
+}
+
+func TestReadXMLMissingNode(t *testing.T) {
+
+	// the extract was cut between nodes 2 and 3
+	doc := `<osm version="0.6">
+  <node id="1" lat="51.500" lon="-0.100"/>
+  <node id="2" lat="51.501" lon="-0.100"/>
+  <node id="4" lat="51.501" lon="-0.101"/>
+  <way id="10">
+    <nd ref="1"/><nd ref="2"/><nd ref="3"/>
+    <tag k="highway" v="footway"/>
+  </way>
+</osm>`
+
+	bounds := overpass.Bounds{South: 51.49, West: -0.11, North: 51.51, East: -0.09}
+
+	res, err := ReadXML(strings.NewReader(doc), bounds, overpass.Pedestrian)
+
+	if err != nil {
+		t.Fatalf("ReadXML() returned error %v", err)
+	}
+
+	// node 4 is not used by any way and node 3 is missing
+	if len(res.Elements) != 3 || res.Elements[0].Id != 10 || res.Elements[1].Id != 1 || res.Elements[2].Id != 2 {
+		t.Errorf("ReadXML() == %+v, want way 10 with nodes 1 and 2", res.Elements)
+	}
+}
@@ -0,0 +1,340 @@
+package osmfile
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/yurachistic1/routeplanner-backend/overpass"
+)
+
+// Limits from the PBF specification, larger blobs indicate a corrupt file.
+const (
+	maxHeaderSize = 64 * 1024
+	maxBlobSize   = 32 * 1024 * 1024
+)
+
+// Features that a reader has to understand in order to read a file.
+var supportedFeatures = map[string]bool{
+	"OsmSchema-V0.6": true,
+	"DenseNodes":     true,
+}
+
+// ReadPBF reads an OSM PBF file, see Read. Only zlib compressed and raw blobs
+// are supported which covers extracts produced by common tools.
+func ReadPBF(r io.ReadSeeker, area overpass.Area, filter overpass.Filter) (overpass.Response, error) {
+
+	e, err := readTwice(r, filter, readPBF)
+
+	if err != nil {
+		return overpass.Response{}, err
+	}
+
+	return e.response(area), nil
+}
+
+// ReadPBF passes the nodes and ways of the file to e.
+func readPBF(r io.Reader, e *extract) error {
+
+	for {
+		blobType, blob, err := readBlob(r)
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		switch blobType {
+		case "OSMHeader":
+			err = checkHeader(blob)
+		case "OSMData":
+			err = readPrimitiveBlock(blob, e)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadBlob reads a single BlobHeader and Blob pair and returns the type and
+// uncompressed data of the blob.
+func readBlob(r io.Reader) (blobType string, data []byte, err error) {
+
+	var size uint32
+
+	if err = binary.Read(r, binary.BigEndian, &size); err != nil {
+		return
+	}
+
+	if size > maxHeaderSize {
+		return "", nil, fmt.Errorf("pbf blob header too large: %d bytes", size)
+	}
+
+	header := make([]byte, size)
+
+	if _, err = io.ReadFull(r, header); err != nil {
+		return "", nil, unexpected(err)
+	}
+
+	var dataSize uint64
+
+	err = message(header).each(func(field int, m value) error {
+		switch field {
+		case 1:
+			blobType = string(m.bytes())
+		case 3:
+			dataSize = m.varint()
+		}
+		return nil
+	})
+
+	if err != nil {
+		return
+	}
+
+	if dataSize > maxBlobSize {
+		return "", nil, fmt.Errorf("pbf blob too large: %d bytes", dataSize)
+	}
+
+	blob := make([]byte, dataSize)
+
+	if _, err = io.ReadFull(r, blob); err != nil {
+		return "", nil, unexpected(err)
+	}
+
+	var raw, compressed []byte
+	var compression int
+
+	err = message(blob).each(func(field int, m value) error {
+		switch field {
+		case 1:
+			raw = m.bytes()
+		case 3:
+			compressed = m.bytes()
+		case 2:
+			// raw_size
+		default:
+			compression = field
+		}
+		return nil
+	})
+
+	switch {
+	case err != nil:
+		return
+	case raw != nil:
+		return blobType, raw, nil
+	case compressed != nil:
+		zr, err := zlib.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return "", nil, err
+		}
+		defer zr.Close()
+		data, err = ioutil.ReadAll(zr)
+		return blobType, data, err
+	}
+
+	return "", nil, fmt.Errorf("unsupported pbf blob compression (field %d)", compression)
+}
+
+// CheckHeader returns an error if the file requires features this reader does
+// not implement.
+func checkHeader(data []byte) error {
+	return message(data).each(func(field int, m value) error {
+		if field == 4 && !supportedFeatures[string(m.bytes())] {
+			return fmt.Errorf("unsupported pbf feature %q", m.bytes())
+		}
+		return nil
+	})
+}
+
+// PrimitiveBlock holds the fields shared by all the groups of a data blob.
+type primitiveBlock struct {
+	strings     []string
+	granularity int64
+	latOffset   int64
+	lonOffset   int64
+}
+
+func (b *primitiveBlock) coord(offset, value int64) float64 {
+	return 1e-9 * float64(offset+b.granularity*value)
+}
+
+func readPrimitiveBlock(data []byte, e *extract) error {
+
+	block := primitiveBlock{granularity: 100}
+	var groups []value
+
+	err := message(data).each(func(field int, m value) error {
+		switch field {
+		case 1:
+			return m.each(func(field int, s value) error {
+				if field == 1 {
+					block.strings = append(block.strings, string(s.bytes()))
+				}
+				return nil
+			})
+		case 2:
+			groups = append(groups, m)
+		case 17:
+			block.granularity = int64(m.varint())
+		case 19:
+			block.latOffset = int64(m.varint())
+		case 20:
+			block.lonOffset = int64(m.varint())
+		}
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	// groups can only be decoded once the string table is known
+	for _, group := range groups {
+		err := group.each(func(field int, m value) error {
+			switch field {
+			case 1:
+				return block.readNode(m, e)
+			case 2:
+				return block.readDenseNodes(m, e)
+			case 3:
+				return block.readWay(m, e)
+			}
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *primitiveBlock) readNode(data value, e *extract) error {
+
+	var id, lat, lon int64
+
+	err := data.each(func(field int, m value) error {
+		switch field {
+		case 1:
+			id = m.sint()
+		case 8:
+			lat = m.sint()
+		case 9:
+			lon = m.sint()
+		}
+		return nil
+	})
+
+	if err == nil {
+		e.addNode(int(id), b.coord(b.latOffset, lat), b.coord(b.lonOffset, lon))
+	}
+
+	return err
+}
+
+func (b *primitiveBlock) readDenseNodes(data value, e *extract) error {
+
+	var ids, lats, lons []int64
+
+	err := data.each(func(field int, m value) (err error) {
+		switch field {
+		case 1:
+			ids, err = m.sints(ids)
+		case 8:
+			lats, err = m.sints(lats)
+		case 9:
+			lons, err = m.sints(lons)
+		}
+		return
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if len(lats) != len(ids) || len(lons) != len(ids) {
+		return errors.New("pbf dense nodes have mismatched lengths")
+	}
+
+	// values are delta coded
+	var id, lat, lon int64
+
+	for i := range ids {
+		id, lat, lon = id+ids[i], lat+lats[i], lon+lons[i]
+		e.addNode(int(id), b.coord(b.latOffset, lat), b.coord(b.lonOffset, lon))
+	}
+
+	return nil
+}
+
+func (b *primitiveBlock) readWay(data value, e *extract) error {
+
+	var (
+		id         int64
+		keys, vals []uint64
+		deltas     []int64
+	)
+
+	err := data.each(func(field int, m value) (err error) {
+		switch field {
+		case 1:
+			id = int64(m.varint())
+		case 2:
+			keys, err = m.varints(keys)
+		case 3:
+			vals, err = m.varints(vals)
+		case 8:
+			deltas, err = m.sints(deltas)
+		}
+		return
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if len(keys) != len(vals) {
+		return fmt.Errorf("pbf way %d has mismatched tags", id)
+	}
+
+	tags := make(map[string]string, len(keys))
+
+	for i := range keys {
+		if keys[i] >= uint64(len(b.strings)) || vals[i] >= uint64(len(b.strings)) {
+			return fmt.Errorf("pbf way %d references missing string", id)
+		}
+		tags[b.strings[keys[i]]] = b.strings[vals[i]]
+	}
+
+	refs := make([]int, len(deltas))
+	var ref int64
+
+	for i, delta := range deltas {
+		ref += delta
+		refs[i] = int(ref)
+	}
+
+	e.addWay(int(id), tags, refs)
+
+	return nil
+}
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
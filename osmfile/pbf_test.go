@@ -0,0 +1,145 @@
+package osmfile
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/yurachistic1/routeplanner-backend/overpass"
+)
+
+// Minimal protocol buffer encoder used to build test files.
+type encoder []byte
+
+func (e encoder) key(field, wire int) encoder {
+	return e.uvarint(uint64(field<<3 | wire))
+}
+
+func (e encoder) uvarint(v uint64) encoder {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(e, buf[:n]...)
+}
+
+func (e encoder) varint(field int, v uint64) encoder {
+	return e.key(field, wireVarint).uvarint(v)
+}
+
+func (e encoder) bytes(field int, b []byte) encoder {
+	return append(e.key(field, wireBytes).uvarint(uint64(len(b))), b...)
+}
+
+func (e encoder) packed(field int, vals []int64) encoder {
+	var p encoder
+	for _, v := range vals {
+		p = p.uvarint(uint64((v << 1) ^ (v >> 63)))
+	}
+	return e.bytes(field, p)
+}
+
+func writeBlob(buf *bytes.Buffer, blobType string, data []byte, compress bool) {
+
+	var blob encoder
+
+	if compress {
+		var z bytes.Buffer
+		w := zlib.NewWriter(&z)
+		w.Write(data)
+		w.Close()
+		blob = blob.varint(2, uint64(len(data))).bytes(3, z.Bytes())
+	} else {
+		blob = blob.bytes(1, data)
+	}
+
+	header := encoder{}.bytes(1, []byte(blobType)).varint(3, uint64(len(blob)))
+
+	binary.Write(buf, binary.BigEndian, uint32(len(header)))
+	buf.Write(header)
+	buf.Write(blob)
+}
+
+func testPBF(features ...string) []byte {
+
+	var buf bytes.Buffer
+
+	var header encoder
+	for _, f := range features {
+		header = header.bytes(4, []byte(f))
+	}
+	writeBlob(&buf, "OSMHeader", header, false)
+
+	var strings encoder
+	for _, s := range []string{"", "highway", "footway", "primary"} {
+		strings = strings.bytes(1, []byte(s))
+	}
+
+	// nodes 1, 2 and 3 at 51.5, 51.501 and 51.6 with granularity of 100
+	dense := encoder{}.
+		packed(1, []int64{1, 1, 1}).
+		packed(8, []int64{515000000, 10000, 990000}).
+		packed(9, []int64{-1000000, 0, 0})
+
+	footway := encoder{}.varint(1, 10).
+		bytes(2, encoder{}.uvarint(1)).
+		bytes(3, encoder{}.uvarint(2)).
+		packed(8, []int64{1, 1})
+
+	primary := encoder{}.varint(1, 11).
+		bytes(2, encoder{}.uvarint(1)).
+		bytes(3, encoder{}.uvarint(3)).
+		packed(8, []int64{2, 1})
+
+	group := encoder{}.bytes(2, dense).bytes(3, footway).bytes(3, primary)
+
+	block := encoder{}.bytes(1, strings).bytes(2, group)
+
+	writeBlob(&buf, "OSMData", block, true)
+
+	return buf.Bytes()
+}
+
+func TestReadPBF(t *testing.T) {
+
+	bounds := overpass.Bounds{South: 51.49, West: -0.11, North: 51.51, East: -0.09}
+
+	res, err := ReadPBF(bytes.NewReader(testPBF("OsmSchema-V0.6", "DenseNodes")), bounds, overpass.Pedestrian)
+
+	if err != nil {
+		t.Fatalf("ReadPBF() returned error %v", err)
+	}
+
+	if len(res.Elements) != 3 {
+		t.Fatalf("ReadPBF() == %+v, want a way and 2 nodes", res.Elements)
+	}
+
+	way := res.Elements[0]
+
+	if way.Type != "way" || way.Id != 10 || way.Tags["highway"] != "footway" ||
+		len(way.Nodes) != 2 || way.Nodes[0] != 1 || way.Nodes[1] != 2 {
+		t.Errorf("ReadPBF() way == %+v, want footway 10 with nodes [1 2]", way)
+	}
+
+	n := res.Elements[2]
+
+	if n.Id != 2 || math.Abs(n.Lat-51.501) > 1e-9 || math.Abs(n.Lon+0.1) > 1e-9 {
+		t.Errorf("ReadPBF() node == %+v, want node 2 at 51.501, -0.1", n)
+	}
+}
+
+func TestReadPBFUnsupported(t *testing.T) {
+
+	_, err := ReadPBF(bytes.NewReader(testPBF("OsmSchema-V0.6", "HistoricalInformation")), overpass.Bounds{}, overpass.Pedestrian)
+
+	if err == nil {
+		t.Errorf("ReadPBF() returned no error for unsupported feature")
+	}
+
+	data := testPBF("OsmSchema-V0.6")
+	_, err = ReadPBF(bytes.NewReader(data[:len(data)-5]), overpass.Bounds{}, overpass.Pedestrian)
+
+	if err == nil {
+		t.Errorf("ReadPBF() returned no error for truncated file")
+	}
+}
@@ -0,0 +1,133 @@
+package osmfile
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Wire types of the protocol buffer encoding.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("truncated pbf message")
+
+// Message is an encoded protocol buffer message. PBF files only use a handful
+// of simple messages so they are decoded field by field instead of relying on
+// generated code.
+type message []byte
+
+// Value is a single field of a message.
+type value struct {
+	wire int
+	num  uint64
+	data []byte
+}
+
+// Each calls fn for every field of the message in the order they are encoded.
+func (m message) each(fn func(field int, v value) error) error {
+
+	for len(m) > 0 {
+		key, n := binary.Uvarint(m)
+		if n <= 0 {
+			return errTruncated
+		}
+		m = m[n:]
+
+		v := value{wire: int(key & 7)}
+
+		switch v.wire {
+		case wireVarint:
+			v.num, n = binary.Uvarint(m)
+			if n <= 0 {
+				return errTruncated
+			}
+		case wireFixed64:
+			if len(m) < 8 {
+				return errTruncated
+			}
+			v.num, n = binary.LittleEndian.Uint64(m), 8
+		case wireFixed32:
+			if len(m) < 4 {
+				return errTruncated
+			}
+			v.num, n = uint64(binary.LittleEndian.Uint32(m)), 4
+		case wireBytes:
+			size, k := binary.Uvarint(m)
+			if k <= 0 || uint64(len(m)-k) < size {
+				return errTruncated
+			}
+			v.data, n = m[k:k+int(size)], k+int(size)
+		default:
+			return fmt.Errorf("unsupported pbf wire type %d", v.wire)
+		}
+
+		m = m[n:]
+
+		if err := fn(int(key>>3), v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Each decodes the value as an embedded message.
+func (v value) each(fn func(field int, v value) error) error {
+	return message(v.data).each(fn)
+}
+
+func (v value) bytes() []byte {
+	return v.data
+}
+
+func (v value) varint() uint64 {
+	return v.num
+}
+
+// Sint decodes a zigzag encoded signed integer.
+func (v value) sint() int64 {
+	return zigzag(v.num)
+}
+
+// Varints appends a repeated varint field to dst. Repeated fields are usually
+// packed but a single unpacked element is valid too.
+func (v value) varints(dst []uint64) ([]uint64, error) {
+
+	if v.wire == wireVarint {
+		return append(dst, v.num), nil
+	}
+
+	data := v.data
+
+	for len(data) > 0 {
+		num, n := binary.Uvarint(data)
+		if n <= 0 {
+			return dst, errTruncated
+		}
+		dst = append(dst, num)
+		data = data[n:]
+	}
+
+	return dst, nil
+}
+
+// Sints appends a repeated zigzag encoded field to dst.
+func (v value) sints(dst []int64) ([]int64, error) {
+
+	nums, err := v.varints(nil)
+
+	for _, num := range nums {
+		dst = append(dst, zigzag(num))
+	}
+
+	return dst, err
+}
+
+func zigzag(num uint64) int64 {
+	return int64(num>>1) ^ -int64(num&1)
+}
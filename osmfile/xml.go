@@ -0,0 +1,105 @@
+package osmfile
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+
+	"github.com/yurachistic1/routeplanner-backend/overpass"
+)
+
+// ReadXML reads an OSM XML document, see Read.
+func ReadXML(r io.ReadSeeker, area overpass.Area, filter overpass.Filter) (overpass.Response, error) {
+
+	e, err := readTwice(r, filter, readXML)
+
+	if err != nil {
+		return overpass.Response{}, err
+	}
+
+	return e.response(area), nil
+}
+
+// ReadXML passes the nodes and ways of the document to e.
+func readXML(r io.Reader, e *extract) error {
+
+	decoder := xml.NewDecoder(r)
+
+	var (
+		inWay bool
+		id    int
+		tags  map[string]string
+		refs  []int
+	)
+
+	for {
+		token, err := decoder.Token()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "node":
+				var lat, lon float64
+				if id, err = intAttr(t, "id"); err == nil {
+					if lat, err = floatAttr(t, "lat"); err == nil {
+						lon, err = floatAttr(t, "lon")
+					}
+				}
+				if err != nil {
+					return err
+				}
+				e.addNode(id, lat, lon)
+			case "way":
+				if id, err = intAttr(t, "id"); err != nil {
+					return err
+				}
+				inWay, tags, refs = true, make(map[string]string), []int{}
+			case "nd":
+				if !inWay {
+					continue
+				}
+				ref, err := intAttr(t, "ref")
+				if err != nil {
+					return err
+				}
+				refs = append(refs, ref)
+			case "tag":
+				if inWay {
+					tags[attr(t, "k")] = attr(t, "v")
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local == "way" {
+				e.addWay(id, tags, refs)
+				inWay = false
+			}
+		}
+	}
+
+	return nil
+}
+
+func attr(t xml.StartElement, name string) string {
+	for _, a := range t.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func intAttr(t xml.StartElement, name string) (int, error) {
+	return strconv.Atoi(attr(t, name))
+}
+
+func floatAttr(t xml.StartElement, name string) (float64, error) {
+	return strconv.ParseFloat(attr(t, name), 64)
+}
@@ -0,0 +1,195 @@
+package overpass
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Km per degree of latitude, also of longitude at the equator.
+const kmPerDegree = 111.32
+
+// Area restricts an overpass query to a region.
+type Area interface {
+	// Bounds returns the smallest Bounds containing the area.
+	Bounds() Bounds
+
+	// Contains reports whether a point lies within the area.
+	Contains(lat, lon float64) bool
+
+	// overlaps reports whether the area may overlap with b. It is allowed
+	// to report false positives.
+	overlaps(b Bounds) bool
+
+	// setting returns a global setting of the statement, such as [bbox:...],
+	// or an empty string.
+	setting() string
+
+	// filter returns a filter appended to every query statement, such as
+	// (around:...), or an empty string.
+	filter() string
+}
+
+// Bounds is a rectangular area given by decimal degree coordinates of its
+// edges.
+type Bounds struct {
+	South float64
+	West  float64
+	North float64
+	East  float64
+}
+
+// NewBounds returns Bounds of a square given coordinates of its center and
+// side length in km.
+func NewBounds(lat float64, lon float64, side float64) Bounds {
+	return Bounds{
+		South: lat - ((side / 2) / kmPerDegree),
+		North: lat + ((side / 2) / kmPerDegree),
+		West:  lon - ((side / 2) / (kmPerDegree * math.Cos(lat*(math.Pi/180)))),
+		East:  lon + ((side / 2) / (kmPerDegree * math.Cos(lat*(math.Pi/180)))),
+	}
+}
+
+// String returns the bounds as a bbox setting of an overpass QL statement.
+func (b Bounds) String() string {
+	return fmt.Sprintf("[bbox:%f,%f,%f,%f]", b.South, b.West, b.North, b.East)
+}
+
+func (b Bounds) Bounds() Bounds { return b }
+
+func (b Bounds) Contains(lat, lon float64) bool {
+	return lat >= b.South && lat <= b.North && lon >= b.West && lon <= b.East
+}
+
+func (b Bounds) overlaps(o Bounds) bool {
+	return b.South <= o.North && o.South <= b.North &&
+		b.West <= o.East && o.West <= b.East
+}
+
+func (b Bounds) setting() string { return b.String() }
+func (b Bounds) filter() string  { return "" }
+
+// TileArea is a rectangular area that restricts every statement with a bbox
+// filter instead of a global setting, so that several of them can be used in
+// one query.
+type tileArea Bounds
+
+func (t tileArea) Bounds() Bounds                 { return Bounds(t) }
+func (t tileArea) Contains(lat, lon float64) bool { return Bounds(t).Contains(lat, lon) }
+func (t tileArea) overlaps(b Bounds) bool         { return Bounds(t).overlaps(b) }
+func (t tileArea) setting() string                { return "" }
+
+func (t tileArea) filter() string {
+	return fmt.Sprintf("(%f,%f,%f,%f)", t.South, t.West, t.North, t.East)
+}
+
+// Around is a disc given by coordinates of its center and radius in meters.
+type Around struct {
+	Lat    float64
+	Lon    float64
+	Radius float64
+}
+
+func (a Around) Bounds() Bounds {
+	return NewBounds(a.Lat, a.Lon, 2*a.Radius/1000)
+}
+
+func (a Around) Contains(lat, lon float64) bool {
+	return a.distance(lat, lon) <= a.Radius
+}
+
+// Overlaps checks the distance to the point of b closest to the center.
+func (a Around) overlaps(b Bounds) bool {
+	lat := math.Max(b.South, math.Min(a.Lat, b.North))
+	lon := math.Max(b.West, math.Min(a.Lon, b.East))
+	return a.Contains(lat, lon)
+}
+
+// Distance returns an equirectangular approximation of the distance in meters
+// between the center and a point, accurate enough at the scale of a run.
+func (a Around) distance(lat, lon float64) float64 {
+	x := (lon - a.Lon) * math.Cos((lat+a.Lat)/2*math.Pi/180)
+	y := lat - a.Lat
+	return math.Sqrt(x*x+y*y) * kmPerDegree * 1000
+}
+
+func (a Around) setting() string { return "" }
+
+func (a Around) filter() string {
+	return fmt.Sprintf("(around:%.0f,%f,%f)", a.Radius, a.Lat, a.Lon)
+}
+
+// Point is a pair of decimal degree coordinates.
+type Point struct {
+	Lat float64
+	Lon float64
+}
+
+// Polygon is an area enclosed by a list of points, the last point is
+// implicitly connected to the first one.
+type Polygon []Point
+
+func (p Polygon) Bounds() Bounds {
+	b := Bounds{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, pt := range p {
+		b.South, b.North = math.Min(b.South, pt.Lat), math.Max(b.North, pt.Lat)
+		b.West, b.East = math.Min(b.West, pt.Lon), math.Max(b.East, pt.Lon)
+	}
+	return b
+}
+
+// Contains uses the even-odd rule, points on the edge may go either way.
+func (p Polygon) Contains(lat, lon float64) bool {
+	inside := false
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		if (p[i].Lat > lat) != (p[j].Lat > lat) &&
+			lon < (p[j].Lon-p[i].Lon)*(lat-p[i].Lat)/(p[j].Lat-p[i].Lat)+p[i].Lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func (p Polygon) overlaps(b Bounds) bool {
+	return p.Bounds().overlaps(b)
+}
+
+func (p Polygon) setting() string { return "" }
+
+func (p Polygon) filter() string {
+	coords := make([]string, len(p))
+	for i, pt := range p {
+		coords[i] = fmt.Sprintf("%f %f", pt.Lat, pt.Lon)
+	}
+	return fmt.Sprintf("(poly:\"%s\")", strings.Join(coords, " "))
+}
+
+// Crosses reports whether the segment between a and b crosses any of the
+// sides of the polygon.
+func (p Polygon) Crosses(a, b Point) bool {
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		if intersect(a, b, p[j], p[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Intersect reports whether segments a-b and c-d properly intersect,
+// latitude and longitude are treated as plane coordinates.
+func intersect(a, b, c, d Point) bool {
+	return orientation(a, b, c)*orientation(a, b, d) < 0 &&
+		orientation(c, d, a)*orientation(c, d, b) < 0
+}
+
+// Orientation returns the sign of the cross product of b-a and c-a.
+func orientation(a, b, c Point) float64 {
+	cross := (b.Lon-a.Lon)*(c.Lat-a.Lat) - (b.Lat-a.Lat)*(c.Lon-a.Lon)
+	switch {
+	case cross > 0:
+		return 1
+	case cross < 0:
+		return -1
+	}
+	return 0
+}
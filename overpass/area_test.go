@@ -0,0 +1,103 @@
+package overpass
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAreaContains(t *testing.T) {
+
+	around := Around{51.27, 0.19, 1000}
+	triangle := Polygon{{51.0, 0.0}, {51.0, 1.0}, {52.0, 0.0}}
+	l := Polygon{{0, 0}, {0, 2}, {1, 2}, {1, 1}, {2, 1}, {2, 0}}
+
+	cases := []struct {
+		area Area
+		lat  float64
+		lon  float64
+		want bool
+	}{
+		{around, 51.27, 0.19, true},
+		{around, 51.278, 0.19, true},
+		{around, 51.28, 0.19, false},
+		{around, 51.27, 0.203, true},
+		{around, 51.27, 0.205, false},
+		{around, 51.277, 0.2, false},
+		{triangle, 51.2, 0.2, true},
+		{triangle, 51.8, 0.8, false},
+		{triangle, 50.9, 0.2, false},
+		{l, 0.5, 1.5, true},
+		{l, 1.5, 0.5, true},
+		{l, 1.5, 1.5, false},
+		{Bounds{51, 0, 52, 1}, 51.8, 0.8, true},
+	}
+
+	for _, c := range cases {
+		if result := c.area.Contains(c.lat, c.lon); result != c.want {
+			t.Errorf("%v.Contains(%v, %v) == %v, want %v", c.area, c.lat, c.lon, result, c.want)
+		}
+
+		if c.want && !c.area.Bounds().Contains(c.lat, c.lon) {
+			t.Errorf("%v.Bounds() does not contain %v, %v", c.area, c.lat, c.lon)
+		}
+	}
+}
+
+func TestPolygonCrosses(t *testing.T) {
+
+	square := Polygon{{0, 0}, {0, 1}, {1, 1}, {1, 0}}
+
+	cases := []struct {
+		a, b Point
+		want bool
+	}{
+		{Point{-1, 0.5}, Point{2, 0.5}, true},
+		{Point{0.5, 0.5}, Point{0.5, 2}, true},
+		{Point{0.2, 0.2}, Point{0.8, 0.8}, false},
+		{Point{2, 0}, Point{2, 1}, false},
+	}
+
+	for _, c := range cases {
+		if result := square.Crosses(c.a, c.b); result != c.want {
+			t.Errorf("Crosses(%v, %v) == %v, want %v", c.a, c.b, result, c.want)
+		}
+	}
+}
+
+func TestAroundTiles(t *testing.T) {
+
+	cache := &TileCache{TileSize: 0.01}
+
+	around := Around{51.27, 0.19, 3000}
+	square := around.Bounds()
+
+	discTiles, squareTiles := cache.Tiles(around), cache.Tiles(square)
+
+	if len(discTiles) >= len(squareTiles) {
+		t.Errorf("Tiles(%v) == %d tiles, want fewer than the %d of its bounds",
+			around, len(discTiles), len(squareTiles))
+	}
+
+	for _, tile := range squareTiles {
+		if tile.Contains(around.Lat, around.Lon) {
+			found := false
+			for _, d := range discTiles {
+				found = found || d == tile
+			}
+			if !found {
+				t.Errorf("Tiles(%v) does not contain the center tile", around)
+			}
+		}
+	}
+}
+
+func TestPolygonQL(t *testing.T) {
+
+	ql := Pedestrian.QL(Polygon{{51.0, 0.0}, {51.0, 1.0}, {52.0, 0.0}})
+
+	want := `(poly:"51.000000 0.000000 51.000000 1.000000 52.000000 0.000000");`
+
+	if !strings.Contains(ql, want) || strings.Contains(ql, "[bbox:") {
+		t.Errorf("QL() == %s, want poly filter %s", ql, want)
+	}
+}
@@ -0,0 +1,477 @@
+package overpass
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// TileCache stores overpass responses on disk split into tiles of a fixed
+// grid. A query for any area is answered by combining the tiles it overlaps,
+// so nearby requests share downloads and only missing tiles are fetched, all
+// of them with a single query.
+type TileCache struct {
+	Client *Client
+
+	// Dir is where tile responses are stored, one file per tile and query.
+	Dir string
+
+	// TTL is how long a stored tile is used before it is fetched again.
+	TTL time.Duration
+
+	// TileSize is the side of a grid cell in decimal degrees.
+	TileSize float64
+
+	// MaxSize caps the total size of the stored tiles in bytes, the least
+	// recently fetched tiles are removed first. Zero means no limit.
+	MaxSize int64
+}
+
+// NewTileCache returns a TileCache that fetches missing tiles with client and
+// stores them in dir. Tiles are about 1.1 by 0.7 km at 51°N, close to the
+// area a short loop needs. The cache is kept under 64 MB as the temporary
+// directory may be held in memory.
+func NewTileCache(client *Client, dir string) *TileCache {
+	return &TileCache{
+		Client:   client,
+		Dir:      dir,
+		TTL:      time.Hour * 24,
+		TileSize: 0.01,
+		MaxSize:  64 << 20,
+	}
+}
+
+// Query returns a Response with the ways selected by filter that have a node
+// within the area, together with their nodes.
+func (c *TileCache) Query(ctx context.Context, area Area, filter Filter) (response Response, err error) {
+
+	elements := []Element{}
+
+	response, err = c.Stream(ctx, area, filter, func(e *Element) error {
+		elements = append(elements, *e)
+		return nil
+	})
+
+	response.Elements = elements
+
+	return
+}
+
+// Stream is like Query but passes elements to fn one at a time as they are
+// decoded from the stored tiles, see DecodeElements. Tiles extend beyond the
+// area, so ways without any node inside the area are left out and so are
+// nodes that none of the passed ways use. Elements shared by several tiles,
+// e.g. ways crossing a tile edge, are only passed on once. The cache is
+// pruned after new tiles were fetched.
+func (c *TileCache) Stream(ctx context.Context, area Area, filter Filter, fn func(*Element) error) (response Response, err error) {
+
+	if err = os.MkdirAll(c.Dir, 0755); err != nil {
+		return
+	}
+
+	tiles := c.Tiles(area)
+
+	// tiles are kept open while they are read, so that they can still be
+	// read when they are pruned by a concurrent request
+	files := make([]*os.File, len(tiles))
+
+	defer func() {
+		for _, f := range files {
+			if f != nil {
+				f.Close()
+			}
+		}
+	}()
+
+	missing := []Bounds{}
+
+	for i, tile := range tiles {
+		if files[i] = c.open(c.path(filter.QL(tile))); files[i] == nil {
+			missing = append(missing, tile)
+		}
+	}
+
+	if len(missing) > 0 {
+		if err = c.fetch(ctx, filter, missing); err != nil {
+			return
+		}
+
+		for i, tile := range tiles {
+			if files[i] == nil {
+				if files[i], err = os.Open(c.path(filter.QL(tile))); err != nil {
+					return
+				}
+			}
+		}
+
+		// pruning waits until the tiles of this area have been read
+		defer c.Prune()
+	}
+
+	ways := make(map[int]bool)
+	nodes := make(map[int]bool)
+
+	// nodes of passed ways that are yet to be passed
+	needed := make(map[int]bool)
+
+	for i, file := range files {
+		// the first pass finds the nodes of the tile inside the area
+		inside := make(map[int]bool)
+
+		_, err = c.decode(file, func(e *Element) error {
+			if e.Type == "node" && area.Contains(e.Lat, e.Lon) {
+				inside[e.Id] = true
+			}
+			return nil
+		})
+
+		if err != nil {
+			return
+		}
+
+		header, err := c.decode(file, func(e *Element) error {
+			switch e.Type {
+			case "way":
+				if ways[e.Id] || !anyInside(e.Nodes, inside) {
+					return nil
+				}
+				ways[e.Id] = true
+				for _, id := range e.Nodes {
+					if !nodes[id] {
+						needed[id] = true
+					}
+				}
+			case "node":
+				if !needed[e.Id] {
+					return nil
+				}
+				delete(needed, e.Id)
+				nodes[e.Id] = true
+			default:
+				return nil
+			}
+			return fn(e)
+		})
+
+		if err != nil {
+			return response, err
+		}
+
+		if i == 0 {
+			response = header
+		}
+	}
+
+	return
+}
+
+func anyInside(ids []int, inside map[int]bool) bool {
+	for _, id := range ids {
+		if inside[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// Tiles returns the grid cells that overlap with the area.
+func (c *TileCache) Tiles(area Area) (tiles []Bounds) {
+
+	size := c.TileSize
+	bounds := area.Bounds()
+
+	for y := math.Floor(bounds.South / size); y*size < bounds.North; y++ {
+		for x := math.Floor(bounds.West / size); x*size < bounds.East; x++ {
+			tile := Bounds{
+				South: y * size,
+				West:  x * size,
+				North: (y + 1) * size,
+				East:  (x + 1) * size,
+			}
+
+			if area.overlaps(tile) {
+				tiles = append(tiles, tile)
+			}
+		}
+	}
+
+	return
+}
+
+// Prune removes all the stored tiles that have outlived the TTL and then the
+// least recently fetched ones until the cache fits in MaxSize.
+func (c *TileCache) Prune() error {
+
+	files, err := filepath.Glob(filepath.Join(c.Dir, "*.json"))
+
+	if err != nil {
+		return err
+	}
+
+	kept := []os.FileInfo{}
+	var size int64
+
+	for _, file := range files {
+		info, err := os.Stat(file)
+
+		if err != nil {
+			continue
+		}
+
+		if time.Since(info.ModTime()) > c.TTL {
+			os.Remove(file)
+			continue
+		}
+
+		kept = append(kept, info)
+		size += info.Size()
+	}
+
+	if c.MaxSize <= 0 {
+		return nil
+	}
+
+	sort.Slice(kept, func(i, j int) bool {
+		return kept[i].ModTime().Before(kept[j].ModTime())
+	})
+
+	for i := 0; i < len(kept) && size > c.MaxSize; i++ {
+		if err := os.Remove(filepath.Join(c.Dir, kept[i].Name())); err == nil {
+			size -= kept[i].Size()
+		}
+	}
+
+	return nil
+}
+
+// Path returns the file a tile query is stored in.
+func (c *TileCache) path(query string) string {
+	sum := sha1.Sum([]byte(query))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Open returns a stored tile that has not expired, or nil if there is none.
+func (c *TileCache) open(file string) *os.File {
+
+	f, err := os.Open(file)
+
+	if err != nil {
+		return nil
+	}
+
+	if info, err := f.Stat(); err != nil || time.Since(info.ModTime()) > c.TTL {
+		f.Close()
+		return nil
+	}
+
+	return f
+}
+
+// Decode streams the elements of a stored tile from its start. A tile that
+// cannot be read is removed so that it is fetched again next time.
+func (c *TileCache) decode(f *os.File, fn func(*Element) error) (Response, error) {
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return Response{}, err
+	}
+
+	response, err := DecodeElements(f, fn)
+
+	if errors.Is(err, ErrMalformedResponse) {
+		os.Remove(f.Name())
+	}
+
+	return response, err
+}
+
+// Fetch downloads the tiles with a single query, see Filter.tilesQL, and
+// writes each of them to disk as the response arrives. Tiles are written
+// under temporary names and only renamed once the whole response was
+// decoded without errors, so that concurrent readers never see a partial
+// tile.
+func (c *TileCache) fetch(ctx context.Context, filter Filter, tiles []Bounds) error {
+
+	return c.Client.do(ctx, func(api string) error {
+
+		writers := make([]*tileWriter, len(tiles))
+
+		defer func() {
+			for _, w := range writers {
+				if w != nil {
+					w.discard()
+				}
+			}
+		}()
+
+		for i := range writers {
+			w, err := newTileWriter(c.Dir)
+			if err != nil {
+				return err
+			}
+			writers[i] = w
+		}
+
+		body, err := open(ctx, c.Client.HTTPClient, api, filter.tilesQL(tiles, c.queryTimeout()))
+
+		if err != nil {
+			return err
+		}
+
+		defer body.Close()
+
+		var current *tileWriter
+
+		header, err := DecodeElements(body, func(e *Element) error {
+			if e.Type == "tile" {
+				i, err := strconv.Atoi(e.Tags["index"])
+				if err != nil || i < 0 || i >= len(writers) {
+					return malformed(fmt.Errorf("unexpected tile %q", e.Tags["index"]))
+				}
+				current = writers[i]
+				return nil
+			}
+
+			if current == nil {
+				return malformed(errors.New("element before the first tile"))
+			}
+
+			return current.add(e)
+		})
+
+		if err != nil {
+			return err
+		}
+
+		for i, w := range writers {
+			if err := w.finish(header, c.path(filter.QL(tiles[i]))); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Time left for transferring a response after the server finished the query.
+const transferTime = time.Second * 10
+
+// QueryTimeout returns the longest server side timeout in seconds of a query
+// for several tiles. The http client has to wait for the response, if it
+// gave up first the query would be cut off and repeated in vain.
+func (c *TileCache) queryTimeout() int {
+
+	limit := maxTimeout
+
+	if t := c.Client.HTTPClient.Timeout; t > 0 {
+		if seconds := int((t - transferTime) / time.Second); seconds < limit {
+			limit = seconds
+		}
+	}
+
+	if limit < 1 {
+		limit = 1
+	}
+
+	return limit
+}
+
+// TileWriter stores the elements of a single tile in the format of an
+// overpass response. Only the fields a Filter query outputs are kept.
+type tileWriter struct {
+	file  *os.File
+	buf   *bufio.Writer
+	count int
+}
+
+// StoredElement is the JSON form of an element in a stored tile.
+type storedElement struct {
+	Type  string            `json:"type"`
+	Id    int               `json:"id"`
+	Lat   float64           `json:"lat,omitempty"`
+	Lon   float64           `json:"lon,omitempty"`
+	Tags  map[string]string `json:"tags,omitempty"`
+	Nodes []int             `json:"nodes,omitempty"`
+}
+
+func newTileWriter(dir string) (*tileWriter, error) {
+
+	file, err := ioutil.TempFile(dir, "tile-*.tmp")
+
+	if err != nil {
+		return nil, err
+	}
+
+	w := &tileWriter{file: file, buf: bufio.NewWriter(file)}
+	w.buf.WriteString(`{"elements":[`)
+
+	return w, nil
+}
+
+func (w *tileWriter) add(e *Element) error {
+
+	data, err := json.Marshal(storedElement{e.Type, e.Id, e.Lat, e.Lon, e.Tags, e.Nodes})
+
+	if err != nil {
+		return err
+	}
+
+	if w.count > 0 {
+		w.buf.WriteByte(',')
+	}
+	w.count++
+
+	_, err = w.buf.Write(data)
+	return err
+}
+
+// Finish writes the rest of the response and moves the tile to file.
+func (w *tileWriter) finish(header Response, file string) error {
+
+	rest, err := json.Marshal(struct {
+		Version   float32 `json:"version"`
+		Generator string  `json:"generator"`
+		Meta      Meta    `json:"osm3s"`
+	}{header.Version, header.Generator, header.Meta})
+
+	if err != nil {
+		return err
+	}
+
+	w.buf.WriteString("],")
+	w.buf.Write(rest[1:])
+
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(w.file.Name(), file); err != nil {
+		return err
+	}
+
+	w.file = nil
+	return nil
+}
+
+// Discard removes the temporary file of an unfinished tile.
+func (w *tileWriter) discard() {
+	if w.file != nil {
+		w.file.Close()
+		os.Remove(w.file.Name())
+	}
+}
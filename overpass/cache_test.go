@@ -0,0 +1,298 @@
+package overpass
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTiles(t *testing.T) {
+
+	cache := &TileCache{TileSize: 0.5}
+
+	cases := []struct {
+		in   Bounds
+		want int
+	}{
+		{Bounds{51.1, -0.4, 51.2, -0.3}, 1},
+		{Bounds{51.1, -0.6, 51.2, -0.3}, 2},
+		{Bounds{50.9, -0.6, 51.2, 0.1}, 6},
+		{Bounds{51.0, 0.0, 51.5, 0.5}, 1},
+	}
+
+	for _, c := range cases {
+		tiles := cache.Tiles(c.in)
+
+		if len(tiles) != c.want {
+			t.Errorf("Tiles(%v) == %v, want %d tiles", c.in, tiles, c.want)
+		}
+
+		for _, tile := range tiles {
+			if tile.North < c.in.South || tile.South > c.in.North ||
+				tile.East < c.in.West || tile.West > c.in.East {
+				t.Errorf("Tiles(%v) contains %v which does not overlap", c.in, tile)
+			}
+		}
+	}
+}
+
+// tileServer answers tile queries like Filter.tilesQL renders them. Every
+// tile contains way 1 between nodes 1 and 2 inside the test area, a way from
+// node 1 to a node unique to the tile and a way far from the area.
+func tileServer(queries *[]string, remark string) *httptest.Server {
+
+	served := 0
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.FormValue("data")
+		*queries = append(*queries, query)
+
+		elements := []string{}
+
+		for i := 0; i < strings.Count(query, "make tile"); i++ {
+			u, v := 1000+served, 2000+2*served
+			served++
+
+			elements = append(elements,
+				fmt.Sprintf(`{"type": "tile", "id": %d, "tags": {"index": "%d"}}`, i+1, i),
+				`{"type": "way", "id": 1, "nodes": [1, 2]}`,
+				fmt.Sprintf(`{"type": "way", "id": %d, "nodes": [1, %d]}`, u, u),
+				fmt.Sprintf(`{"type": "way", "id": %d, "nodes": [%d, %d]}`, v, v, v+1),
+				`{"type": "node", "id": 1, "lat": 51.15, "lon": -0.05}`,
+				`{"type": "node", "id": 2, "lat": 51.15, "lon": 0.05}`,
+				fmt.Sprintf(`{"type": "node", "id": %d, "lat": 51.01, "lon": 0}`, u),
+				fmt.Sprintf(`{"type": "node", "id": %d, "lat": 10, "lon": 10}`, v),
+				fmt.Sprintf(`{"type": "node", "id": %d, "lat": 10, "lon": 10.1}`, v+1),
+			)
+		}
+
+		fmt.Fprintf(w, `{"version": 0.6, "elements": [%s], "remark": %q}`, strings.Join(elements, ","), remark)
+	}))
+}
+
+func TestTileCacheQuery(t *testing.T) {
+
+	var queries []string
+
+	server := tileServer(&queries, "")
+	defer server.Close()
+
+	cache := NewTileCache(newTestClient(server.URL), t.TempDir())
+	cache.TileSize = 0.5
+
+	filter := Filter{Include: []Selector{{{"highway", Exists, ""}}}}
+
+	count := func(res Response) (ways, nodes int) {
+		for _, e := range res.Elements {
+			if e.Type == "way" {
+				ways++
+			} else {
+				nodes++
+			}
+		}
+		return
+	}
+
+	res, err := cache.Query(context.Background(), Bounds{51.1, -0.4, 51.2, 0.1}, filter)
+
+	if err != nil {
+		t.Fatalf("Query() returned error %v", err)
+	}
+
+	// both tiles are fetched with one query
+	if len(queries) != 1 || strings.Count(queries[0], "make tile") != 2 {
+		t.Fatalf("queries == %q, want one for 2 tiles", queries)
+	}
+
+	// the far way and its nodes are left out, way 1 and node 1 only once
+	if ways, nodes := count(res); ways != 3 || nodes != 4 || res.Version != 0.6 {
+		t.Errorf("Query() returned %d ways and %d nodes, version %v, want 3, 4, 0.6", ways, nodes, res.Version)
+	}
+
+	// two tiles are shared with the previous query
+	res, err = cache.Query(context.Background(), Bounds{51.1, -0.1, 51.6, 0.1}, filter)
+
+	if err != nil {
+		t.Fatalf("Query() returned error %v", err)
+	}
+
+	if len(queries) != 2 || strings.Count(queries[1], "make tile") != 2 {
+		t.Errorf("queries == %q, want another one for 2 tiles", queries)
+	}
+
+	if ways, nodes := count(res); ways != 5 || nodes != 6 {
+		t.Errorf("Query() returned %d ways and %d nodes, want 5, 6", ways, nodes)
+	}
+
+	// nothing is fetched when all the tiles are stored
+	cache.Query(context.Background(), Bounds{51.1, -0.1, 51.2, 0.1}, filter)
+
+	if len(queries) != 2 {
+		t.Errorf("%d queries sent, want 2", len(queries))
+	}
+
+	// expired tiles are fetched again
+	cache.TTL = 0
+	cache.Query(context.Background(), Bounds{51.1, -0.1, 51.2, 0.1}, filter)
+
+	if len(queries) != 3 {
+		t.Errorf("%d queries sent, want 3", len(queries))
+	}
+}
+
+func TestTileCacheFailedQuery(t *testing.T) {
+
+	var queries []string
+
+	server := tileServer(&queries, "runtime error: Query timed out")
+	defer server.Close()
+
+	dir := t.TempDir()
+	cache := NewTileCache(newTestClient(server.URL), dir)
+	cache.Client.Retries = 0
+	cache.TileSize = 0.5
+
+	_, err := cache.Query(context.Background(), Bounds{51.1, -0.4, 51.2, 0.1}, Pedestrian)
+
+	if !errors.Is(err, ErrServerTimeout) {
+		t.Errorf("Query() returned error %v, want a timeout", err)
+	}
+
+	// no tile of the incomplete response is stored
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Errorf("%d files stored, want none", len(files))
+	}
+}
+
+func TestTileCachePrune(t *testing.T) {
+
+	var queries []string
+
+	server := tileServer(&queries, "")
+	defer server.Close()
+
+	dir := t.TempDir()
+	cache := NewTileCache(newTestClient(server.URL), dir)
+	cache.TileSize = 0.5
+	cache.MaxSize = 250
+
+	now := time.Now()
+
+	for i, age := range []time.Duration{time.Hour * 48, time.Hour * 3, time.Hour * 2, time.Hour} {
+		file := filepath.Join(dir, fmt.Sprintf("%d.json", i))
+		ioutil.WriteFile(file, make([]byte, 100), 0644)
+		os.Chtimes(file, now.Add(-age), now.Add(-age))
+	}
+
+	if err := cache.Prune(); err != nil {
+		t.Fatalf("Prune() returned error %v", err)
+	}
+
+	// the expired tile and then the oldest one are removed
+	for i, want := range []bool{false, false, true, true} {
+		if _, err := os.Stat(filepath.Join(dir, fmt.Sprintf("%d.json", i))); (err == nil) != want {
+			t.Errorf("tile %d kept == %v, want %v", i, err == nil, want)
+		}
+	}
+
+	// fetching new tiles prunes the cache but keeps what was just read
+	cache.MaxSize = 0
+	cache.TTL = time.Minute * 90
+
+	if _, err := cache.Query(context.Background(), Bounds{51.1, -0.4, 51.2, 0.1}, Pedestrian); err != nil {
+		t.Fatalf("Query() returned error %v", err)
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+
+	if len(files) != 3 {
+		t.Errorf("%d files stored, want the 2 fetched tiles and 1 fresh one", len(files))
+	}
+}
+
+func TestTileCacheQueryTimeout(t *testing.T) {
+
+	var queries []string
+
+	server := tileServer(&queries, "")
+	defer server.Close()
+
+	cache := NewTileCache(newTestClient(server.URL), t.TempDir())
+	cache.TileSize = 0.5
+	cache.TTL = 0
+
+	area := Bounds{51.1, -0.4, 51.6, 0.1}
+
+	cases := []struct {
+		timeout time.Duration
+		want    string
+	}{
+		// the server gives up before the http client does
+		{time.Second * 60, "[timeout:50]"},
+		{0, "[timeout:100]"},
+	}
+
+	for _, c := range cases {
+		cache.Client.HTTPClient.Timeout = c.timeout
+
+		if _, err := cache.Query(context.Background(), area, Pedestrian); err != nil {
+			t.Fatalf("Query() returned error %v", err)
+		}
+
+		if query := queries[len(queries)-1]; !strings.HasPrefix(query, "[out:json]"+c.want+";") {
+			t.Errorf("http timeout %v: query starts with %q, want %s", c.timeout, strings.SplitN(query, "\n", 2)[0], c.want)
+		}
+	}
+}
+
+func TestTileCachePrunedWhileReading(t *testing.T) {
+
+	var queries []string
+
+	server := tileServer(&queries, "")
+	defer server.Close()
+
+	dir := t.TempDir()
+	cache := NewTileCache(newTestClient(server.URL), dir)
+	cache.TileSize = 0.5
+
+	area := Bounds{51.1, -0.4, 51.2, 0.1}
+
+	if _, err := cache.Query(context.Background(), area, Pedestrian); err != nil {
+		t.Fatalf("Query() returned error %v", err)
+	}
+
+	// a concurrent request prunes the cache as soon as reading has started
+	pruned := false
+	ways := 0
+
+	_, err := cache.Stream(context.Background(), area, Pedestrian, func(e *Element) error {
+		if !pruned {
+			files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+			for _, file := range files {
+				os.Remove(file)
+			}
+			pruned = true
+		}
+		if e.Type == "way" {
+			ways++
+		}
+		return nil
+	})
+
+	if err != nil || ways != 3 {
+		t.Errorf("Stream() passed %d ways, returned error %v, want 3 ways and no error", ways, err)
+	}
+
+	if len(queries) != 1 {
+		t.Errorf("%d queries sent, want 1", len(queries))
+	}
+}
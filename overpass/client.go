@@ -0,0 +1,279 @@
+package overpass
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Client queries a list of overpass api instances. Failed requests are retried
+// with exponential backoff before failing over to the next instance. Health and
+// latency of every instance are tracked so that the best one is tried first.
+type Client struct {
+	HTTPClient *http.Client
+
+	// Retries is the number of additional attempts made against an instance
+	// before moving on to the next one.
+	Retries int
+
+	// Backoff is the delay before the first retry, doubled on every retry.
+	Backoff time.Duration
+
+	// Cooldown is how long an instance that has just failed is ranked below
+	// the healthy ones. It is doubled for every consecutive failure.
+	Cooldown time.Duration
+
+	mu      sync.Mutex
+	mirrors []*Mirror
+}
+
+// Mirror holds health statistics of a single overpass api instance.
+type Mirror struct {
+	Api                 string
+	Successes           int
+	Failures            int
+	ConsecutiveFailures int
+	LastFailure         time.Time
+
+	// Latency is an exponentially weighted moving average of the time taken
+	// by successful requests.
+	Latency time.Duration
+}
+
+// NewClient returns a Client for the supplied instances, e.g. Main or KumiSys.
+// Until any statistics are gathered the instances are tried in the order given.
+func NewClient(apis ...string) *Client {
+	c := &Client{
+		HTTPClient: &http.Client{Timeout: time.Second * 60},
+		Retries:    1,
+		Backoff:    time.Second,
+		Cooldown:   time.Minute,
+	}
+
+	for _, api := range apis {
+		c.mirrors = append(c.mirrors, &Mirror{Api: api})
+	}
+
+	return c
+}
+
+// Query sends the query to the best available instance and returns the first
+// successful Response. If every instance fails the last error is returned.
+func (c *Client) Query(query string) (Response, error) {
+	return c.QueryContext(context.Background(), query)
+}
+
+// QueryContext is like Query but gives up as soon as ctx is done.
+func (c *Client) QueryContext(ctx context.Context, query string) (response Response, err error) {
+
+	err = c.do(ctx, func(api string) (err error) {
+		response, err = queryWith(ctx, c.HTTPClient, api, query)
+		return err
+	})
+
+	return response, err
+}
+
+// Stream sends the query like QueryContext but passes elements to fn as they
+// are decoded instead of collecting them, see DecodeElements. Once fn has
+// been called the request is no longer retried as fn would see duplicates.
+func (c *Client) Stream(ctx context.Context, query string, fn func(*Element) error) (response Response, err error) {
+
+	err = c.do(ctx, func(api string) error {
+		delivered := false
+
+		err := streamWith(ctx, c.HTTPClient, api, query, func(e *Element) error {
+			delivered = true
+			return fn(e)
+		}, &response)
+
+		if err != nil && delivered {
+			return &partialError{err}
+		}
+
+		return err
+	})
+
+	return response, err
+}
+
+// Do calls attempt with the best available instances until it succeeds,
+// retrying and failing over on errors that are likely to be temporary.
+func (c *Client) do(ctx context.Context, attempt func(api string) error) (err error) {
+
+	for _, api := range c.ranked() {
+		for i := 0; i <= c.Retries; i++ {
+			if i > 0 {
+				if err := sleep(ctx, c.Backoff*time.Duration(1<<(i-1))); err != nil {
+					return err
+				}
+			}
+
+			start := time.Now()
+			err = attempt(api)
+
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			// errors caused by the query itself say nothing about the
+			// health of the instance
+			if err == nil || retryable(err) {
+				c.record(api, time.Since(start), err)
+			}
+
+			if err == nil {
+				return nil
+			}
+
+			if partial, ok := err.(*partialError); ok {
+				return partial.err
+			}
+
+			if !retryable(err) {
+				return err
+			}
+		}
+	}
+
+	if err == nil {
+		err = errors.New("no overpass api instances configured")
+	}
+
+	return err
+}
+
+// PartialError marks an error that occurred after some of the response had
+// already been used, so the request cannot be repeated.
+type partialError struct {
+	err error
+}
+
+func (e *partialError) Error() string {
+	return e.err.Error()
+}
+
+func (e *partialError) Unwrap() error {
+	return e.err
+}
+
+// Stats returns a snapshot of the instance statistics in the order they would
+// be tried next.
+func (c *Client) Stats() (stats []Mirror) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, m := range c.sorted() {
+		stats = append(stats, *m)
+	}
+
+	return stats
+}
+
+// Ranked returns api addresses in the order they should be tried.
+func (c *Client) ranked() (apis []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, m := range c.sorted() {
+		apis = append(apis, m.Api)
+	}
+
+	return apis
+}
+
+// Sorted returns mirrors ordered by health and then latency. Mirrors without
+// any successful requests keep their original relative order after the
+// measured ones. Must be called with c.mu held.
+func (c *Client) sorted() []*Mirror {
+	now := time.Now()
+
+	cooling := func(m *Mirror) bool {
+		if m.ConsecutiveFailures == 0 {
+			return false
+		}
+		shift := math.Min(float64(m.ConsecutiveFailures-1), 10)
+		return now.Sub(m.LastFailure) < c.Cooldown*time.Duration(1<<int(shift))
+	}
+
+	latency := func(m *Mirror) time.Duration {
+		if m.Successes == 0 {
+			return time.Duration(math.MaxInt64)
+		}
+		return m.Latency
+	}
+
+	mirrors := append([]*Mirror{}, c.mirrors...)
+
+	sort.SliceStable(mirrors, func(i, j int) bool {
+		ci, cj := cooling(mirrors[i]), cooling(mirrors[j])
+		if ci != cj {
+			return cj
+		}
+		return latency(mirrors[i]) < latency(mirrors[j])
+	})
+
+	return mirrors
+}
+
+// Record updates statistics of an instance after a request.
+func (c *Client) record(api string, elapsed time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, m := range c.mirrors {
+		if m.Api != api {
+			continue
+		}
+
+		if err != nil {
+			m.Failures++
+			m.ConsecutiveFailures++
+			m.LastFailure = time.Now()
+			return
+		}
+
+		if m.Successes == 0 {
+			m.Latency = elapsed
+		} else {
+			m.Latency = (m.Latency*7 + elapsed) / 8
+		}
+		m.Successes++
+		m.ConsecutiveFailures = 0
+		return
+	}
+}
+
+// Retryable reports whether a request that failed with err could succeed if
+// it was sent again or to another instance.
+func retryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests ||
+			statusErr.StatusCode >= 500
+	}
+
+	var remarkErr *RemarkError
+	if errors.As(err, &remarkErr) {
+		return errors.Is(err, ErrServerTimeout)
+	}
+
+	return true
+}
+
+// Sleep pauses for duration d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
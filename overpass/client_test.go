@@ -0,0 +1,151 @@
+package overpass
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const okBody = `{"version": 0.6, "elements": [{"type": "node", "id": 1, "lat": 51.5, "lon": -0.1}]}`
+
+func newTestClient(apis ...string) *Client {
+	c := NewClient(apis...)
+	c.Backoff = time.Millisecond
+	return c
+}
+
+func statusServer(code int, hits *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*hits++
+		w.WriteHeader(code)
+		if code == http.StatusOK {
+			fmt.Fprint(w, okBody)
+		}
+	}))
+}
+
+func TestClientFailover(t *testing.T) {
+
+	var busyHits, timeoutHits, okHits int
+
+	busy := statusServer(http.StatusTooManyRequests, &busyHits)
+	defer busy.Close()
+	timeout := statusServer(http.StatusGatewayTimeout, &timeoutHits)
+	defer timeout.Close()
+	ok := statusServer(http.StatusOK, &okHits)
+	defer ok.Close()
+
+	c := newTestClient(busy.URL, timeout.URL, ok.URL)
+
+	res, err := c.Query("[out:json];node(1);out;")
+
+	if err != nil {
+		t.Fatalf("Query() returned error %v", err)
+	}
+
+	if len(res.Elements) != 1 || res.Elements[0].Id != 1 {
+		t.Errorf("Query() == %+v, want a single node with id 1", res)
+	}
+
+	if busyHits != 2 || timeoutHits != 2 || okHits != 1 {
+		t.Errorf("hits == %d, %d, %d, want 2, 2, 1", busyHits, timeoutHits, okHits)
+	}
+
+	// failing instances are cooling down so the healthy one is tried first
+	stats := c.Stats()
+	if stats[0].Api != ok.URL {
+		t.Errorf("Stats()[0].Api == %v, want %v", stats[0].Api, ok.URL)
+	}
+
+	if _, err := c.Query("[out:json];node(1);out;"); err != nil {
+		t.Fatalf("Query() returned error %v", err)
+	}
+
+	if busyHits != 2 || timeoutHits != 2 || okHits != 2 {
+		t.Errorf("hits == %d, %d, %d, want 2, 2, 2", busyHits, timeoutHits, okHits)
+	}
+}
+
+func TestClientBadRequest(t *testing.T) {
+
+	var badHits, okHits int
+
+	bad := statusServer(http.StatusBadRequest, &badHits)
+	defer bad.Close()
+	ok := statusServer(http.StatusOK, &okHits)
+	defer ok.Close()
+
+	c := newTestClient(bad.URL, ok.URL)
+
+	_, err := c.Query("not a query")
+
+	statusErr, isStatus := err.(*StatusError)
+	if !isStatus || statusErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Query() error == %v, want status 400", err)
+	}
+
+	if badHits != 1 || okHits != 0 {
+		t.Errorf("hits == %d, %d, want 1, 0", badHits, okHits)
+	}
+
+	// the query was at fault, not the instance
+	if stats := c.Stats(); stats[0].Api != bad.URL || stats[0].Failures != 0 {
+		t.Errorf("Stats() == %+v, want %v first without failures", stats, bad.URL)
+	}
+}
+
+func TestClientRanking(t *testing.T) {
+
+	c := newTestClient("a", "b", "c", "d")
+
+	c.record("a", 300*time.Millisecond, nil)
+	c.record("b", 100*time.Millisecond, nil)
+	c.record("c", 50*time.Millisecond, &StatusError{http.StatusGatewayTimeout})
+
+	want := []string{"b", "a", "d", "c"}
+	got := c.ranked()
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("ranked() == %v, want %v", got, want)
+		}
+	}
+
+	// cooldown has passed so the failed instance is tried again
+	c.Cooldown = 0
+	want = []string{"b", "a", "c", "d"}
+	got = c.ranked()
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("ranked() == %v, want %v", got, want)
+		}
+	}
+}
+
+func TestClientHeavyQuery(t *testing.T) {
+
+	var hits int
+
+	heavy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		fmt.Fprint(w, `{"elements": [], "remark": "runtime error: Query run out of memory using about 2048 MB of RAM."}`)
+	}))
+	defer heavy.Close()
+
+	c := newTestClient(heavy.URL, "b")
+
+	if _, err := c.Query("[out:json];way;out;"); err == nil {
+		t.Fatalf("Query() returned no error")
+	}
+
+	if hits != 1 {
+		t.Errorf("hits == %d, want 1", hits)
+	}
+
+	if stats := c.Stats(); stats[0].Api != heavy.URL || stats[0].ConsecutiveFailures != 0 {
+		t.Errorf("Stats() == %+v, want %v first and not cooling down", stats, heavy.URL)
+	}
+}
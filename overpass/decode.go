@@ -0,0 +1,123 @@
+package overpass
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DecodeElements reads an overpass JSON response from r and calls fn for every
+// element of the elements array as soon as it is decoded, so the response
+// never has to be held in memory as a whole. The remaining fields are
+// returned in response with Elements left empty. A remark reporting a runtime
+// error is returned as a *RemarkError after all the elements were passed on.
+func DecodeElements(r io.Reader, fn func(*Element) error) (response Response, err error) {
+
+	decoder := json.NewDecoder(r)
+
+	if err = expectDelim(decoder, '{'); err != nil {
+		return
+	}
+
+	for decoder.More() {
+		token, err := decoder.Token()
+
+		if err != nil {
+			return response, malformed(err)
+		}
+
+		key, _ := token.(string)
+
+		switch strings.ToLower(key) {
+		case "version":
+			err = decoder.Decode(&response.Version)
+		case "generator":
+			err = decoder.Decode(&response.Generator)
+		case "osm3s":
+			err = decoder.Decode(&response.Meta)
+		case "remark":
+			err = decoder.Decode(&response.Remark)
+		case "elements":
+			err = decodeArray(decoder, fn)
+		default:
+			var skip json.RawMessage
+			err = decoder.Decode(&skip)
+		}
+
+		var elementErr elementError
+
+		switch {
+		case errors.As(err, &elementErr):
+			return response, elementErr.err
+		case errors.Is(err, ErrMalformedResponse):
+			return response, err
+		case err != nil:
+			return response, malformed(err)
+		}
+	}
+
+	if err = expectDelim(decoder, '}'); err != nil {
+		return
+	}
+
+	if strings.Contains(response.Remark, "error") {
+		return response, &RemarkError{response.Remark}
+	}
+
+	return response, nil
+}
+
+// ElementError carries an error returned by the callback so that it is not
+// reported as a malformed response.
+type elementError struct {
+	err error
+}
+
+func (e elementError) Error() string {
+	return e.err.Error()
+}
+
+func decodeArray(decoder *json.Decoder, fn func(*Element) error) error {
+
+	if err := expectDelim(decoder, '['); err != nil {
+		return err
+	}
+
+	for decoder.More() {
+		element := new(Element)
+
+		if err := decoder.Decode(element); err != nil {
+			return err
+		}
+
+		if err := fn(element); err != nil {
+			return elementError{err}
+		}
+	}
+
+	return expectDelim(decoder, ']')
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+
+	token, err := decoder.Token()
+
+	if err != nil {
+		return malformed(err)
+	}
+
+	if token != delim {
+		return malformed(fmt.Errorf("expected %v, found %v", delim, token))
+	}
+
+	return nil
+}
+
+func malformed(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("%w: %v", ErrMalformedResponse, err)
+}
@@ -0,0 +1,116 @@
+package overpass
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const streamBody = `{
+  "version": 0.6,
+  "generator": "Overpass API",
+  "osm3s": {"timestamp_osm_base": "2021-06-01T12:00:00Z", "copyright": "ODbL"},
+  "elements": [
+    {"type": "way", "id": 10, "nodes": [1, 2], "tags": {"highway": "footway"}},
+    {"type": "node", "id": 1, "lat": 51.5, "lon": -0.1},
+    {"type": "node", "id": 2, "lat": 51.6, "lon": -0.2}
+  ]
+}`
+
+func TestDecodeElements(t *testing.T) {
+
+	var elements []*Element
+
+	res, err := DecodeElements(strings.NewReader(streamBody), func(e *Element) error {
+		elements = append(elements, e)
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("DecodeElements() returned error %v", err)
+	}
+
+	if res.Generator != "Overpass API" || res.Meta.Copyright != "ODbL" || res.Elements != nil {
+		t.Errorf("DecodeElements() header == %+v", res)
+	}
+
+	if len(elements) != 3 {
+		t.Fatalf("DecodeElements() passed on %d elements, want 3", len(elements))
+	}
+
+	// every element is decoded into a fresh value
+	if elements[0].Tags["highway"] != "footway" || elements[1].Tags != nil ||
+		elements[2].Lat != 51.6 || elements[1].Lat != 51.5 {
+		t.Errorf("DecodeElements() elements == %+v, %+v, %+v",
+			elements[0], elements[1], elements[2])
+	}
+}
+
+func TestDecodeElementsErrors(t *testing.T) {
+
+	stop := errors.New("stop")
+
+	cases := []struct {
+		body string
+		fn   func(*Element) error
+		want error
+	}{
+		{streamBody[:200], func(*Element) error { return nil }, ErrMalformedResponse},
+		{`[]`, func(*Element) error { return nil }, ErrMalformedResponse},
+		{streamBody, func(*Element) error { return stop }, stop},
+		{`{"elements": [], "remark": "runtime error: Query timed out"}`,
+			func(*Element) error { return nil }, ErrServerTimeout},
+	}
+
+	for _, c := range cases {
+		_, err := DecodeElements(strings.NewReader(c.body), c.fn)
+
+		if !errors.Is(err, c.want) {
+			t.Errorf("DecodeElements(%q) error == %v, want %v", c.body, err, c.want)
+		}
+	}
+}
+
+func TestClientStream(t *testing.T) {
+
+	var hits int
+
+	// the first instance cuts the response short after some elements
+	truncated := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		fmt.Fprint(w, streamBody[:300])
+	}))
+	defer truncated.Close()
+
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		fmt.Fprint(w, streamBody)
+	}))
+	defer ok.Close()
+
+	count := 0
+	_, err := newTestClient(truncated.URL, ok.URL).Stream(context.Background(), "",
+		func(*Element) error {
+			count++
+			return nil
+		})
+
+	if !errors.Is(err, ErrMalformedResponse) || hits != 1 {
+		t.Errorf("Stream() error == %v after %d requests, want malformed response after 1", err, hits)
+	}
+
+	count = 0
+	_, err = newTestClient(ok.URL).Stream(context.Background(), "",
+		func(*Element) error {
+			count++
+			return nil
+		})
+
+	if err != nil || count != 3 {
+		t.Errorf("Stream() == %v with %d elements, want 3 elements", err, count)
+	}
+}
@@ -0,0 +1,63 @@
+package overpass
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Errors that a query can be matched against with errors.Is.
+var (
+	// ErrRateLimited means the instance is refusing queries because too
+	// many were sent. Retrying later or on another instance may succeed.
+	ErrRateLimited = errors.New("overpass api rate limit exceeded")
+
+	// ErrServerTimeout means the instance gave up on the query, either with
+	// a gateway timeout or a remark saying the query timed out.
+	ErrServerTimeout = errors.New("overpass api timed out")
+
+	// ErrMalformedResponse means the response body is not valid JSON, most
+	// often because the connection was cut midway.
+	ErrMalformedResponse = errors.New("malformed overpass api response")
+)
+
+// StatusError is returned when an overpass api instance responds with a
+// status code other than 200.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("overpass api error: %d %s",
+		e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Unwrap allows rate limiting and timeouts to be matched with errors.Is.
+func (e *StatusError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusGatewayTimeout:
+		return ErrServerTimeout
+	}
+	return nil
+}
+
+// RemarkError is returned when the query failed at runtime on the server and
+// the reason was reported in the remark field of an otherwise valid response.
+type RemarkError struct {
+	Remark string
+}
+
+func (e *RemarkError) Error() string {
+	return "overpass api remark: " + e.Remark
+}
+
+// Unwrap allows runtime timeouts to be matched with errors.Is.
+func (e *RemarkError) Unwrap() error {
+	if strings.Contains(e.Remark, "timed out") {
+		return ErrServerTimeout
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package overpass
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Op is a comparison applied to the value of a tag.
+type Op int
+
+// Tag comparisons, they follow the semantics of overpass QL tag filters.
+const (
+	Exists    Op = iota // ["key"]
+	Equals              // ["key"="value"]
+	NotEquals           // ["key"!="value"], also true when the tag is missing
+	Matches             // ["key"~"regex"]
+)
+
+// Tag is a condition on a single tag of an element.
+type Tag struct {
+	Key   string
+	Op    Op
+	Value string
+}
+
+// Selector matches ways on which all of the tag conditions hold.
+type Selector []Tag
+
+// Filter describes a set of ways as data: the ways matching any of the
+// Include selectors minus the ways matching any of the Exclude selectors.
+type Filter struct {
+	Include []Selector
+	Exclude []Selector
+
+	// ExcludeNearBuildings removes highways that share a node with a
+	// building, such ways usually run inside or along its walls.
+	ExcludeNearBuildings bool
+
+	// Timeout is the server side timeout of the query in seconds.
+	Timeout int
+}
+
+// Pedestrian selects roads and paths that are suitable for walking or running.
+var Pedestrian = Filter{
+	Include: []Selector{
+		{{"highway", Matches, "^(secondary|tertiary|unclassified|residential|living_street|pedestrian|track|footway|steps|path|crossing|trailhead|bridleway)$"}},
+		{{"footway", Matches, "^(sidewalk|crossing)$"}},
+		{{"highway", Exists, ""}, {"sidewalk", Matches, "^(both|right|yes|left)$"}},
+		{{"townpath", Equals, "yes"}},
+		{{"foot", Matches, "^(yes|designated|permissive)$"}},
+		{{"designation", Equals, "public_footpath"}},
+	},
+	Exclude: []Selector{
+		{{"access", Equals, "customers"}},
+		{{"access", Equals, "private"}},
+		{{"area", Equals, "yes"}},
+		{{"foot", Equals, "no"}},
+		{{"indoor", Equals, "yes"}},
+		{{"tunnel", Exists, ""}},
+		{{"route", Equals, "ferry"}},
+		{{"sidewalk", Matches, "^(no|none)$"}},
+	},
+	ExcludeNearBuildings: true,
+	Timeout:              25,
+}
+
+// TrailRunner is Pedestrian without the busier classes of roads that are
+// only included because they tend to have a pavement.
+var TrailRunner = Pedestrian.Without(
+	Selector{{"highway", Matches, "^(secondary|tertiary)$"}},
+)
+
+// Wheelchair is Pedestrian without steps, unpaved surfaces and ways that are
+// explicitly tagged as not accessible by wheelchair.
+var Wheelchair = Pedestrian.Without(
+	Selector{{"highway", Equals, "steps"}},
+	Selector{{"wheelchair", Equals, "no"}},
+	Selector{{"surface", Matches, "^(unpaved|gravel|fine_gravel|dirt|earth|grass|ground|mud|sand|woodchips)$"}},
+)
+
+// With returns a copy of the filter that also includes ways matching any of
+// the selectors.
+func (f Filter) With(include ...Selector) Filter {
+	f.Include = append(append([]Selector{}, f.Include...), include...)
+	return f
+}
+
+// Without returns a copy of the filter that also excludes ways matching any
+// of the selectors.
+func (f Filter) Without(exclude ...Selector) Filter {
+	f.Exclude = append(append([]Selector{}, f.Exclude...), exclude...)
+	return f
+}
+
+// Match reports whether a way with the given tags is selected by the filter.
+// ExcludeNearBuildings cannot be decided from tags alone and is ignored.
+func (f Filter) Match(tags map[string]string) bool {
+
+	include := false
+
+	for _, s := range f.Include {
+		if s.Match(tags) {
+			include = true
+			break
+		}
+	}
+
+	if !include {
+		return false
+	}
+
+	for _, s := range f.Exclude {
+		if s.Match(tags) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Longest server side timeout in seconds of a query for several tiles.
+const maxTimeout = 180
+
+// QL returns the overpass QL statement selecting the ways within area
+// together with their nodes, with JSON specified as the output format.
+func (f Filter) QL(area Area) string {
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "[out:json][timeout:%d]%s;\n", f.timeout(), area.setting())
+	f.writeStatements(&b, area)
+
+	return b.String()
+}
+
+// TilesQL returns a single overpass QL statement that selects the ways of
+// each of the tiles the way QL does for the tile alone. The output of every
+// tile is preceded by an element of type "tile" with the index of the tile
+// in its index tag. The server side timeout grows with the number of tiles
+// up to limit seconds.
+func (f Filter) tilesQL(tiles []Bounds, limit int) string {
+
+	var b strings.Builder
+
+	timeout := f.timeout() * len(tiles)
+	if timeout > limit {
+		timeout = limit
+	}
+
+	fmt.Fprintf(&b, "[out:json][timeout:%d];\n", timeout)
+
+	for i, tile := range tiles {
+		fmt.Fprintf(&b, "make tile index=\"%d\";\n", i)
+		b.WriteString("out;\n")
+		f.writeStatements(&b, tileArea(tile))
+	}
+
+	return b.String()
+}
+
+func (f Filter) timeout() int {
+	if f.Timeout == 0 {
+		return 25
+	}
+	return f.Timeout
+}
+
+// WriteStatements writes the statements selecting and printing the ways
+// within area and their nodes.
+func (f Filter) writeStatements(b *strings.Builder, area Area) {
+
+	b.WriteString("(\n")
+	for _, s := range f.Include {
+		fmt.Fprintf(b, "  way%s%s;\n", s, area.filter())
+	}
+	b.WriteString(")->.include;\n")
+
+	b.WriteString("(\n")
+	for _, s := range f.Exclude {
+		fmt.Fprintf(b, "  way%s%s;\n", s, area.filter())
+	}
+	b.WriteString(")->.exclude;\n")
+
+	b.WriteString("(.include; - .exclude;)->.ways;\n")
+
+	if f.ExcludeNearBuildings {
+		b.WriteString("(\n")
+		fmt.Fprintf(b, "  way[\"building\"][\"building\"!=\"no\"]%s;\n", area.filter())
+		b.WriteString("  node(w);\n")
+		b.WriteString("  way[\"highway\"](bn);\n")
+		b.WriteString(")->.buildings;\n")
+		b.WriteString("(.ways; - .buildings;)->.ways;\n")
+	}
+
+	b.WriteString(".ways out;\n")
+	b.WriteString(".ways >;\n")
+	b.WriteString("out skel qt;\n")
+}
+
+// String returns the selector as a sequence of overpass QL tag filters.
+func (s Selector) String() string {
+	var b strings.Builder
+	for _, t := range s {
+		b.WriteString(t.String())
+	}
+	return b.String()
+}
+
+// Match reports whether all of the tag conditions hold.
+func (s Selector) Match(tags map[string]string) bool {
+	for _, t := range s {
+		if !t.Match(tags) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the tag condition as an overpass QL tag filter.
+func (t Tag) String() string {
+	switch t.Op {
+	case Equals:
+		return fmt.Sprintf("[%s=%s]", quote(t.Key), quote(t.Value))
+	case NotEquals:
+		return fmt.Sprintf("[%s!=%s]", quote(t.Key), quote(t.Value))
+	case Matches:
+		return fmt.Sprintf("[%s~%s]", quote(t.Key), quote(t.Value))
+	}
+	return fmt.Sprintf("[%s]", quote(t.Key))
+}
+
+// Match reports whether the tag condition holds.
+func (t Tag) Match(tags map[string]string) bool {
+	value, ok := tags[t.Key]
+
+	switch t.Op {
+	case Equals:
+		return ok && value == t.Value
+	case NotEquals:
+		return value != t.Value
+	case Matches:
+		return ok && compile(t.Value).MatchString(value)
+	}
+	return ok
+}
+
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// Regular expressions are compiled once as filters are matched against every
+// way of an extract.
+var regexps sync.Map
+
+func compile(expr string) *regexp.Regexp {
+	if re, ok := regexps.Load(expr); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(expr)
+	regexps.Store(expr, re)
+	return re
+}
@@ -0,0 +1,134 @@
+package overpass
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFilterMatch(t *testing.T) {
+
+	cases := []struct {
+		filter Filter
+		tags   map[string]string
+		want   bool
+	}{
+		{Pedestrian, map[string]string{"highway": "footway"}, true},
+		{Pedestrian, map[string]string{"highway": "primary"}, false},
+		{Pedestrian, map[string]string{"highway": "primary", "sidewalk": "both"}, true},
+		{Pedestrian, map[string]string{"highway": "primary", "foot": "yes"}, true},
+		{Pedestrian, map[string]string{"designation": "public_footpath"}, true},
+		{Pedestrian, map[string]string{"sidewalk": "both"}, false},
+		{Pedestrian, map[string]string{"highway": "path", "foot": "no"}, false},
+		{Pedestrian, map[string]string{"highway": "path", "tunnel": "culvert"}, false},
+		{Pedestrian, map[string]string{"highway": "pedestrian", "area": "yes"}, false},
+		{Pedestrian, map[string]string{"highway": "residential", "sidewalk": "none"}, false},
+		{Pedestrian, map[string]string{"route": "ferry", "foot": "yes"}, false},
+		{TrailRunner, map[string]string{"highway": "secondary"}, false},
+		{TrailRunner, map[string]string{"highway": "track"}, true},
+		{Wheelchair, map[string]string{"highway": "steps"}, false},
+		{Wheelchair, map[string]string{"highway": "path", "surface": "gravel"}, false},
+		{Wheelchair, map[string]string{"highway": "footway", "surface": "asphalt"}, true},
+	}
+
+	for _, c := range cases {
+		if result := c.filter.Match(c.tags); result != c.want {
+			t.Errorf("Match(%v) == %v, want %v", c.tags, result, c.want)
+		}
+	}
+}
+
+func TestFilterWithout(t *testing.T) {
+
+	n := len(Pedestrian.Exclude)
+
+	TrailRunner.Without(Selector{{"highway", Equals, "steps"}})
+
+	if len(Pedestrian.Exclude) != n || len(TrailRunner.Exclude) != n+1 {
+		t.Errorf("Without() modified the original filter")
+	}
+}
+
+func TestFilterQL(t *testing.T) {
+
+	cases := []struct {
+		area Area
+		want []string
+	}{
+		{
+			Bounds{51.1, -0.2, 51.2, -0.1},
+			[]string{
+				"[out:json][timeout:25][bbox:51.100000,-0.200000,51.200000,-0.100000];",
+				`  way["highway"~"^(secondary|tertiary|unclassified|residential|living_street|pedestrian|track|footway|steps|path|crossing|trailhead|bridleway)$"];`,
+				`  way["highway"]["sidewalk"~"^(both|right|yes|left)$"];`,
+				`  way["tunnel"];`,
+				`  way["building"]["building"!="no"];`,
+				"(.include; - .exclude;)->.ways;",
+				"(.ways; - .buildings;)->.ways;",
+				"out skel qt;",
+			},
+		},
+		{
+			Around{51.1, -0.2, 2500},
+			[]string{
+				"[out:json][timeout:25];",
+				`  way["townpath"="yes"](around:2500,51.100000,-0.200000);`,
+				`  way["access"="private"](around:2500,51.100000,-0.200000);`,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		ql := Pedestrian.QL(c.area)
+
+		for _, line := range c.want {
+			if !strings.Contains(ql, line+"\n") {
+				t.Errorf("QL(%v) does not contain %q:\n%s", c.area, line, ql)
+			}
+		}
+
+		if strings.Count(ql, "(") != strings.Count(ql, ")") {
+			t.Errorf("QL(%v) has unbalanced parentheses:\n%s", c.area, ql)
+		}
+	}
+
+	if q := (Tag{"name", Equals, `Bob's "Path"`}).String(); q != `["name"="Bob's \"Path\""]` {
+		t.Errorf("Tag.String() == %s, want escaped quotes", q)
+	}
+}
+
+func TestFilterTilesQL(t *testing.T) {
+
+	tiles := []Bounds{{51.1, -0.2, 51.2, -0.1}, {51.1, -0.1, 51.2, 0}}
+
+	ql := Pedestrian.tilesQL(tiles, maxTimeout)
+
+	for _, line := range []string{
+		"[out:json][timeout:50];",
+		`make tile index="0";`,
+		`make tile index="1";`,
+		`  way["townpath"="yes"](51.100000,-0.200000,51.200000,-0.100000);`,
+		`  way["townpath"="yes"](51.100000,-0.100000,51.200000,0.000000);`,
+	} {
+		if !strings.Contains(ql, line+"\n") {
+			t.Errorf("tilesQL() does not contain %q:\n%s", line, ql)
+		}
+	}
+
+	// every tile is printed after its marker
+	if strings.Count(ql, "out skel qt;") != 2 || strings.Index(ql, `index="1"`) < strings.Index(ql, "out skel qt;") {
+		t.Errorf("tilesQL() does not print the tiles in order:\n%s", ql)
+	}
+
+	if strings.Count(ql, "(") != strings.Count(ql, ")") {
+		t.Errorf("tilesQL() has unbalanced parentheses:\n%s", ql)
+	}
+
+	many := make([]Bounds, 20)
+	if ql := Pedestrian.tilesQL(many, maxTimeout); !strings.HasPrefix(ql, "[out:json][timeout:180];") {
+		t.Errorf("tilesQL() of 20 tiles starts with %q, want the longest timeout", strings.SplitN(ql, "\n", 2)[0])
+	}
+
+	if ql := Pedestrian.tilesQL(many, 50); !strings.HasPrefix(ql, "[out:json][timeout:50];") {
+		t.Errorf("tilesQL() of 20 tiles starts with %q, want the limit", strings.SplitN(ql, "\n", 2)[0])
+	}
+}
@@ -3,13 +3,11 @@
 package overpass
 
 import (
-	"encoding/json"
-	"errors"
-	"fmt"
-	"io/ioutil"
-	"math"
+	"context"
+	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
@@ -30,6 +28,10 @@ type Response struct {
 	Generator string
 	Meta      Meta `json:"osm3s"`
 	Elements  []Element
+
+	// Remark is set when the query could not be completed, e.g. because
+	// it ran out of time or memory on the server.
+	Remark string
 }
 
 // Meta contains meta properties and is a field of the Response struct.
@@ -73,34 +75,80 @@ type Member struct {
 //specified as JSON.
 func Query(api string, query string) (response Response, err error) {
 
-	overpassClient := http.Client{
-		Timeout: time.Second * 60,
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*60)
+	defer cancel()
+
+	return QueryContext(ctx, api, query)
+}
+
+// QueryContext is like Query but the request is cancelled as soon as ctx is
+// done. Errors can be inspected with errors.Is against ErrRateLimited,
+// ErrServerTimeout and ErrMalformedResponse or with errors.As against
+// *StatusError and *RemarkError.
+func QueryContext(ctx context.Context, api string, query string) (response Response, err error) {
+	return queryWith(ctx, http.DefaultClient, api, query)
+}
+
+// QueryWith sends the query using the supplied http client.
+func queryWith(ctx context.Context, client *http.Client, api string, query string) (response Response, err error) {
+
+	elements := []Element{}
+
+	err = streamWith(ctx, client, api, query, func(e *Element) error {
+		elements = append(elements, *e)
+		return nil
+	}, &response)
+
+	response.Elements = elements
+
+	return
+}
+
+// StreamWith sends the query using the supplied http client and decodes the
+// response with DecodeElements, the remaining fields are stored in header.
+func streamWith(ctx context.Context, client *http.Client, api string, query string, fn func(*Element) error, header *Response) error {
+
+	body, err := open(ctx, client, api, query)
+
+	if err != nil {
+		return err
 	}
 
-	res, err := overpassClient.PostForm(
+	defer body.Close()
+
+	*header, err = DecodeElements(body, fn)
+
+	return err
+}
+
+// Open sends the query and returns the response body if the request succeeded.
+func open(ctx context.Context, client *http.Client, api string, query string) (io.ReadCloser, error) {
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
 		api,
-		url.Values{"data": []string{query}},
+		strings.NewReader(url.Values{"data": []string{query}}.Encode()),
 	)
 
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	defer res.Body.Close()
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	if res.StatusCode != 200 {
-		return response, errors.New("overpass api error")
-	}
-
-	data, err := ioutil.ReadAll(res.Body)
+	res, err := client.Do(req)
 
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	json.Unmarshal(data, &response)
+	if res.StatusCode != 200 {
+		res.Body.Close()
+		return nil, &StatusError{res.StatusCode}
+	}
 
-	return
+	return res.Body, nil
 }
 
 // BBox returns a bbox setting as a string to be used in an overpass QL statement.
@@ -112,9 +160,5 @@ func Query(api string, query string) (response Response, err error) {
 //southern-most latitude, western-most longitude, northern-most latitude,
 //eastern-most longitude.
 func BBox(lat float64, lon float64, side float64) (bbox string) {
-	south := lat - ((side / 2) / 111.32)
-	north := lat + ((side / 2) / 111.32)
-	west := lon - ((side / 2) / (111.32 * math.Cos(lat*(math.Pi/180))))
-	east := lon + ((side / 2) / (111.32 * math.Cos(lat*(math.Pi/180))))
-	return fmt.Sprintf("[bbox:%f,%f,%f,%f]", south, west, north, east)
+	return NewBounds(lat, lon, side).String()
 }
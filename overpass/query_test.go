@@ -0,0 +1,81 @@
+package overpass
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestQueryContextErrors(t *testing.T) {
+
+	cases := []struct {
+		status int
+		body   string
+		want   error
+	}{
+		{http.StatusTooManyRequests, "", ErrRateLimited},
+		{http.StatusGatewayTimeout, "", ErrServerTimeout},
+		{http.StatusOK, `{"elements": [{"type": "node"`, ErrMalformedResponse},
+		{http.StatusOK,
+			`{"elements": [], "remark": "runtime error: Query timed out in \"query\" at line 3 after 26 seconds."}`,
+			ErrServerTimeout},
+	}
+
+	for _, c := range cases {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(c.status)
+			fmt.Fprint(w, c.body)
+		}))
+
+		_, err := QueryContext(context.Background(), server.URL, "")
+
+		if !errors.Is(err, c.want) {
+			t.Errorf("QueryContext() error == %v, want %v", err, c.want)
+		}
+
+		server.Close()
+	}
+}
+
+func TestQueryContextRemark(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"elements": [], "remark": "runtime error: out of memory"}`)
+	}))
+	defer server.Close()
+
+	_, err := QueryContext(context.Background(), server.URL, "")
+
+	var remarkErr *RemarkError
+	if !errors.As(err, &remarkErr) || remarkErr.Remark != "runtime error: out of memory" {
+		t.Errorf("QueryContext() error == %v, want a RemarkError", err)
+	}
+
+	if errors.Is(err, ErrServerTimeout) {
+		t.Errorf("errors.Is(%v, ErrServerTimeout) == true, want false", err)
+	}
+}
+
+func TestQueryContextCancel(t *testing.T) {
+
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := newTestClient(server.URL, server.URL).QueryContext(ctx, "")
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("QueryContext() error == %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,114 @@
+package routeplanner
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strings"
+)
+
+// Default number of decimal places of polyline coordinates, the precision
+// used by Google. OSRM and Valhalla also use 6.
+const defaultPrecision = 5
+
+// EncodePolylineFormat writes the routes as a Responce in which the path of
+// every route is an encoded polyline.
+func encodePolylineFormat(w io.Writer, out output) error {
+
+	response := routesToResponce(out)
+
+	for i := range response {
+		response[i].Polyline = encodePolyline(response[i].Path, out.Precision)
+		response[i].Path = nil
+	}
+
+	return json.NewEncoder(w).Encode(&response)
+}
+
+// EncodePolyline encodes the points with the encoded polyline algorithm
+// format, each coordinate is rounded to the given number of decimal places
+// and stored as the difference from the previous point.
+func encodePolyline(points []CoordPair, precision int) string {
+
+	factor := math.Pow10(precision)
+
+	var b strings.Builder
+	var previous [2]int64
+
+	for _, point := range points {
+		for i, value := range point {
+			rounded := int64(math.Round(value * factor))
+			encodeValue(&b, rounded-previous[i])
+			previous[i] = rounded
+		}
+	}
+
+	return b.String()
+}
+
+// EncodeValue writes a single signed value, least significant five bit
+// chunks first.
+func encodeValue(b *strings.Builder, value int64) {
+
+	v := value << 1
+	if value < 0 {
+		v = ^v
+	}
+
+	for v >= 0x20 {
+		b.WriteByte(byte(0x20|v&0x1f) + 63)
+		v >>= 5
+	}
+
+	b.WriteByte(byte(v) + 63)
+}
+
+// ErrMalformedPolyline is returned when decoding a string that is not an
+// encoded polyline.
+var errMalformedPolyline = errors.New("malformed polyline")
+
+// DecodePolyline returns the points of an encoded polyline.
+func decodePolyline(s string, precision int) ([]CoordPair, error) {
+
+	factor := math.Pow10(precision)
+
+	points := []CoordPair{}
+	var current [2]int64
+
+	for i := 0; i < len(s); {
+		for j := range current {
+			var v int64
+			shift := uint(0)
+
+			for {
+				if i == len(s) || shift > 60 {
+					return nil, errMalformedPolyline
+				}
+
+				c := int64(s[i]) - 63
+				i++
+
+				if c < 0 || c > 0x3f {
+					return nil, errMalformedPolyline
+				}
+
+				v |= (c & 0x1f) << shift
+				shift += 5
+
+				if c < 0x20 {
+					break
+				}
+			}
+
+			if v&1 == 1 {
+				v = ^v
+			}
+			current[j] += v >> 1
+		}
+
+		points = append(points, CoordPair{float64(current[0]) / factor, float64(current[1]) / factor})
+	}
+
+	return points, nil
+}
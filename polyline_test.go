@@ -0,0 +1,74 @@
+package routeplanner
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestEncodePolyline(t *testing.T) {
+
+	cases := []struct {
+		in        []CoordPair
+		precision int
+		want      string
+	}{
+		// the example from the algorithm's documentation
+		{[]CoordPair{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}}, 5, "_p~iF~ps|U_ulLnnqC_mqNvxq`@"},
+		{[]CoordPair{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}}, 6, "_izlhA~rlgdF_{geC~ywl@_kwzCn`{nI"},
+		{[]CoordPair{{0, 0}}, 5, "??"},
+		{[]CoordPair{}, 5, ""},
+	}
+
+	for _, c := range cases {
+		got := encodePolyline(c.in, c.precision)
+
+		if got != c.want {
+			t.Errorf("encodePolyline(%v, %v) == %q, want %q", c.in, c.precision, got, c.want)
+		}
+
+		decoded, err := decodePolyline(got, c.precision)
+
+		if err != nil || !reflect.DeepEqual(decoded, c.in) {
+			t.Errorf("decodePolyline(%q, %v) == %v, %v, want %v", got, c.precision, decoded, err, c.in)
+		}
+	}
+}
+
+func TestPolylineRoundTrip(t *testing.T) {
+
+	points := []CoordPair{}
+	for _, route := range testRoutes() {
+		for _, node := range route.Path {
+			points = append(points, CoordPair{node.Lat, node.Lon})
+		}
+	}
+	points = append(points, CoordPair{-33.8567844, 151.213108}, CoordPair{51.2700004, 0.1900004})
+
+	for _, precision := range []int{5, 6} {
+		decoded, err := decodePolyline(encodePolyline(points, precision), precision)
+
+		if err != nil || len(decoded) != len(points) {
+			t.Fatalf("decodePolyline() == %v, %v, want %d points", decoded, err, len(points))
+		}
+
+		tolerance := math.Pow10(-precision) / 2
+
+		for i := range points {
+			for j := range points[i] {
+				if math.Abs(decoded[i][j]-points[i][j]) > tolerance {
+					t.Errorf("precision %d: point %v decoded as %v", precision, points[i], decoded[i])
+				}
+			}
+		}
+	}
+}
+
+func TestDecodeMalformedPolyline(t *testing.T) {
+
+	for _, in := range []string{"_p~iF", "_p~iF~ps|U_", "_p~iF ps|U", "~~~~~~~~~~~~~~~~??"} {
+		if got, err := decodePolyline(in, 5); err == nil {
+			t.Errorf("decodePolyline(%q) == %v, want an error", in, got)
+		}
+	}
+}
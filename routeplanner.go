@@ -1,85 +1,91 @@
 package routeplanner
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/gorilla/schema"
 
+	"github.com/yurachistic1/routeplanner-backend/elevation"
 	"github.com/yurachistic1/routeplanner-backend/overpass"
 	"github.com/yurachistic1/routeplanner-backend/routing"
 )
 
-const query = `
-[out:json]
-[timeout:25]
-;
-(
-(
-(
-  (
-	(
-	  (
-		(
-		  (
-			(
-			  (
-				way["highway"~"^(secondary|tertiary|unclassified|residential|living_street|pedestrian|track|footway|steps|path|crossing|trailhead|bridleway)$"];
-				way["footway"~"^(sidewalk|crossing)$"];
-				way
-				  ["highway"]
-				  ["sidewalk"~"^(both|right|yes|left)$"];
-				way["townpath"="yes"];
-				way["foot"~"^(yes|designated|permissive)$"];
-				way["designation"="public_footpath"];
-);
-			  -
-			  way["access"="customers"];
-);
-			-
-			way["access"="private"];
-);
-		  -
-		  way["area"="yes"];
-);
-		-
-		way["foot"="no"];
-);
-	  -
-	  way["indoor"="yes"];
-);
-	-
-	way["tunnel"];
-);
-	- 
-	way["route"="ferry"];
-);
-	- 
-	way["sidewalk"~"^(no|none)$"];
-);
-  -
-  (
-	way
-	  ["building"]
-	  ["building"!="no"];
-	node(w);
-	way
-	  ["highway"]
-	  (bn);
-);
-);
-out;
->;
-out skel qt;
-`
+// client fails over between public overpass api instances, KumiSys being
+// the preferred one.
+var client = overpass.NewClient(
+	overpass.KumiSys,
+	overpass.Main,
+	overpass.French,
+	overpass.Swiss,
+	overpass.Russian,
+	overpass.Taiwan,
+)
+
+// planner is used by RoutePlannerAPI. Map data is cached on the local disk,
+// which is the only writable location in a cloud function.
+var planner = &Planner{
+	Source: &CachedSource{
+		Cache: overpass.NewTileCache(
+			client,
+			filepath.Join(os.TempDir(), "routeplanner-tiles"),
+		),
+		Filter: overpass.Pedestrian,
+	},
+	Elevation: localElevation(),
+}
+
+// localElevation reads elevation models from the directory named by the
+// ROUTEPLANNER_DEM environment variable, routes have no elevation if it is
+// not set.
+func localElevation() elevation.Provider {
+	if dir := os.Getenv("ROUTEPLANNER_DEM"); dir != "" {
+		return elevation.NewLocal(dir)
+	}
+	return nil
+}
 
 type CoordPair [2]float64
 
 type Route struct {
-	Path     []CoordPair `json:"path"`
+	Path     []CoordPair `json:"path,omitempty"`
 	Distance float64     `json:"distance"`
+
+	// Path as an encoded polyline, replaces Path in the polyline format.
+	Polyline string `json:"polyline,omitempty"`
+
+	// Only included when elevation data is available.
+	Elevation *Elevation `json:"elevation,omitempty"`
+
+	// Indices in Path at which the loops of a multiloop route end.
+	Boundaries []int `json:"boundaries,omitempty"`
+
+	// Only included when the request asks for an explanation.
+	Score     float64            `json:"score,omitempty"`
+	Breakdown map[string]float64 `json:"breakdown,omitempty"`
+
+	// Only included when the request asks for directions.
+	Directions []Direction `json:"directions,omitempty"`
+}
+
+// Elevation is the elevation profile of a route. Heights and Distances have
+// an entry for every point of the path, heights are null where they are not
+// known. Distances are from the start and all lengths are in meters.
+type Elevation struct {
+	Heights   []*float64 `json:"heights"`
+	Distances []float64  `json:"distances"`
+	Ascent    float64    `json:"ascent"`
+	Descent   float64    `json:"descent"`
+
+	// MaxGrade is the steepest grade in percent, uphill or downhill.
+	MaxGrade float64 `json:"maxGrade"`
 }
 
 type Responce []Route
@@ -87,11 +93,77 @@ type Responce []Route
 type Request struct {
 	Lat      float64 `schema:"lat,required"`
 	Lon      float64 `schema:"lon,required"`
-	Distance float64 `schema:"distance,required"`
+	Distance float64 `schema:"distance"`
+	Profile  string  `schema:"profile"`
+	Weights  string  `schema:"weights"`
+	Explain  bool    `schema:"explain"`
+	Shape    string  `schema:"shape"`
+	Hills    string  `schema:"hills"`
+	Loops    int     `schema:"loops"`
+
+	// Directions adds turn by turn directions to every route. Their texts
+	// are in the language of Lang, or of the Accept-Language header if it is
+	// not set, see negotiateLocale.
+	Directions     bool   `schema:"directions"`
+	Lang           string `schema:"lang"`
+	AcceptLanguage string `schema:"-"`
+
+	// Format of the response, see formats, and the index of a single route
+	// to respond with. Precision is the number of decimal places of
+	// coordinates in encoded polylines.
+	Format    string `schema:"format"`
+	Index     *int   `schema:"route"`
+	Precision int    `schema:"precision"`
+
+	// Waypoints of a loop as lat,lon pairs, one per via parameter. They are
+	// visited in the given order only if Ordered is set.
+	Via     []string `schema:"via"`
+	Ordered bool     `schema:"ordered"`
+
+	// Areas to avoid as lat,lon,lat,lon,... corners, one per avoid parameter,
+	// and comma separated ids of OSM ways to avoid.
+	Avoid     []string `schema:"avoid"`
+	AvoidWays string   `schema:"avoidways"`
+
+	// When the end is set routes lead from the start to the end instead of
+	// looping back, Distance, Shape, Loops, Via and Ordered must not be set.
+	EndLat       *float64 `schema:"endlat"`
+	EndLon       *float64 `schema:"endlon"`
+	Alternatives *int     `schema:"alternatives"`
 }
 
+// Limits of the number of loops of a multiloop route.
+const (
+	minLoops = 2
+	maxLoops = 4
+)
+
+// Maximum number of waypoints of a loop.
+const maxWaypoints = 5
+
+// Limits of point to point requests.
+const (
+	maxSeparation       = 10000 // meters
+	defaultAlternatives = 3
+	maxAlternatives     = 5
+)
+
 // Handler function that is invoked by GCP.
 func RoutePlannerAPI(w http.ResponseWriter, r *http.Request) {
+	planner.ServeHTTP(w, r)
+}
+
+// Planner is an http.Handler that suggests routes using map data from the
+// configured source.
+type Planner struct {
+	Source DataSource
+
+	// Elevation provides the heights of nodes, routes have no elevation if it
+	// is nil.
+	Elevation elevation.Provider
+}
+
+func (p *Planner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 	w.Header().Set("Access-Control-Allow-Origin", "https://yurachistic1.github.io")
@@ -107,43 +179,543 @@ func RoutePlannerAPI(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, "Error: %s", err)
 		return
 	}
+	req.AcceptLanguage = r.Header.Get("Accept-Language")
+
+	// validate format
+	if _, ok := formats[req.Format]; !ok && req.Format != "" {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintf(w, "Error: %s", errors.New("unknown format"))
+		return
+	}
+
+	if req.Precision != 0 && req.Precision != 5 && req.Precision != 6 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintf(w, "Error: %s", errors.New("polyline precision must be 5 or 6"))
+		return
+	}
+
+	// validate profile
+	profile := routing.Default
+	if req.Profile != "" {
+		var ok bool
+		if profile, ok = routing.Profiles[req.Profile]; !ok {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprintf(w, "Error: %s", errors.New("unknown profile"))
+			return
+		}
+	}
+
+	// validate score weights
+	weights, err := parseWeights(req.Weights)
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintf(w, "Error: %s", err)
+		return
+	}
+
+	// validate hill preference, it applies unless climb is weighted explicitly
+	if req.Hills != "" {
+		weight, ok := routing.Hills[req.Hills]
+
+		if !ok {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprintf(w, "Error: %s", errors.New("unknown hill preference"))
+			return
+		}
+
+		if p.Elevation == nil && weight != 0 {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprintf(w, "Error: %s", errors.New("elevation data is not available"))
+			return
+		}
+
+		if _, ok := weights["climb"]; !ok {
+			weights["climb"] = weight
+		}
+	}
+
+	// validate areas and ways to avoid
+	avoid, err := parseAvoid(req.Avoid, req.AvoidWays)
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintf(w, "Error: %s", err)
+		return
+	}
+
+	if avoid.Contains(req.Lat, req.Lon) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintf(w, "Error: %s", errors.New("start is in an avoided area"))
+		return
+	}
+
+	if req.EndLat != nil || req.EndLon != nil {
+		p.servePath(w, r, req, profile, weights, avoid)
+		return
+	}
 
 	// validate distance
 	if req.Distance < 1 || req.Distance > 10 {
 		w.WriteHeader(http.StatusUnprocessableEntity)
 		fmt.Fprintf(w, "Error: %s", errors.New("invalid distance"))
+		return
+	}
+
+	// validate shape
+	shape := routing.Loop
+	if req.Shape != "" {
+		var ok bool
+		if shape, ok = routing.Shapes[req.Shape]; !ok {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprintf(w, "Error: %s", errors.New("unknown shape"))
+			return
+		}
+	}
+
+	// validate number of loops
+	if req.Loops != 0 && shape != routing.MultiLoop {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintf(w, "Error: %s", errors.New("loops requires the multiloop shape"))
+		return
+	}
+
+	if req.Loops != 0 && (req.Loops < minLoops || req.Loops > maxLoops) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintf(w, "Error: %s", errors.New("invalid number of loops"))
+		return
+	}
+
+	// validate waypoints
+	waypoints, err := parseWaypoints(req.Via)
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintf(w, "Error: %s", err)
+		return
+	}
+
+	if err := checkWaypoints(req.Lat, req.Lon, req.Distance*1000, shape, waypoints, avoid); err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintf(w, "Error: %s", err)
+		return
+	}
+
+	// profile weights apply before the requested ones
+	scorer, err := profile.Scorer(routing.DefaultScorer)
+	if err == nil {
+		scorer, err = scorer.WithWeights(weights)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintf(w, "Error: %s", err)
+		return
+	}
+
+	// request map data
+	graph, all, ok := p.fetch(w, r, searchArea(req.Lat, req.Lon, req.Distance*1000, shape, waypoints), avoid)
+	if !ok {
+		return
+	}
+
+	// calculate routes
+	opts := routing.Options{
+		Profile:   profile,
+		Scorer:    scorer,
+		Shape:     shape,
+		Loops:     req.Loops,
+		Waypoints: waypoints,
+		Ordered:   req.Ordered,
+	}
+
+	routes := routing.TopRoutes(req.Lat, req.Lon, req.Distance*1000, graph, opts)
+
+	if len(routes) == 0 && len(waypoints) > 0 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintf(w, "Error: %s", errors.New("waypoints cannot be visited within the distance"))
+		return
+	}
+
+	// blame the avoided areas and ways only if there are routes without them
+	if len(routes) == 0 && !avoid.Empty() &&
+		len(routing.TopRoutes(req.Lat, req.Lon, req.Distance*1000, all, opts)) > 0 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintf(w, "Error: %s", errAvoided)
+		return
+	}
+
+	if len(routes) == 0 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintf(w, "Error: %s", errNoRoutes)
+		return
+	}
+
+	// Send response back to client in the requested format
+	respond(w, req, routes, p.Elevation != nil)
+}
+
+// errNoRoutes is reported when no routes of the requested shape fit the map
+// data, e.g. when there are not enough separate loops through the start.
+var errNoRoutes = errors.New("no routes found")
+
+// ServePath responds with the shortest route from the start to the end of the
+// request followed by alternatives.
+func (p *Planner) servePath(w http.ResponseWriter, r *http.Request, req Request, profile *routing.Profile, weights map[string]float64, avoid routing.Avoid) {
+
+	// validate end
+	if req.EndLat == nil || req.EndLon == nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintf(w, "Error: %s", errors.New("both endlat and endlon are required"))
+		return
+	}
+
+	if avoid.Contains(*req.EndLat, *req.EndLon) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintf(w, "Error: %s", errors.New("end is in an avoided area"))
+		return
+	}
+
+	// parameters of loops have no meaning for a route to the end
+	if req.Distance != 0 || req.Shape != "" || req.Loops != 0 || len(req.Via) > 0 || req.Ordered {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintf(w, "Error: %s", errors.New("distance, shape, loops, via and ordered cannot be used with an end"))
+		return
+	}
+
+	start := &routing.Node{Lat: req.Lat, Lon: req.Lon}
+	end := &routing.Node{Lat: *req.EndLat, Lon: *req.EndLon}
+	separation := routing.Haversine(start, end)
+
+	if separation > maxSeparation {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintf(w, "Error: %s", errors.New("end is too far from the start"))
+		return
 	}
 
-	// request data from overpass api
-	bbox := overpass.BBox(req.Lat, req.Lon, req.Distance*0.8)
+	// validate number of alternatives
+	alternatives := defaultAlternatives
+	if req.Alternatives != nil {
+		alternatives = *req.Alternatives
+	}
 
-	res, err := overpass.Query(overpass.KumiSys, bbox+query)
+	if alternatives < 0 || alternatives > maxAlternatives {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintf(w, "Error: %s", errors.New("invalid number of alternatives"))
+		return
+	}
 
-	if err != nil || res.Elements == nil {
-		w.WriteHeader(http.StatusServiceUnavailable)
+	// profile weights apply before the requested ones
+	scorer, err := profile.Scorer(routing.PathScorer)
+	if err == nil {
+		scorer, err = scorer.WithWeights(weights)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
 		fmt.Fprintf(w, "Error: %s", err)
 		return
 	}
 
-	// process and calculate routes
-	graph := OSMToGraph(res)
+	// request map data
+	graph, all, ok := p.fetch(w, r, pathArea(start, end, separation), avoid)
+	if !ok {
+		return
+	}
+
+	// calculate routes
+	opts := routing.Options{Profile: profile, Scorer: scorer}
+
+	routes, err := routing.ShortestRoutes(start.Lat, start.Lon, end.Lat, end.Lon, graph, alternatives, opts)
 
-	routes := routing.TopRoutes(req.Lat, req.Lon, req.Distance*1000, graph)
+	// blame the avoided areas and ways only if there is a route without them
+	if err != nil && !avoid.Empty() {
+		if _, allErr := routing.ShortestRoutes(start.Lat, start.Lon, end.Lat, end.Lon, all, 0, opts); allErr == nil {
+			err = errAvoided
+		}
+	}
 
-	// Send response back to client as JSON
-	w.WriteHeader(http.StatusOK)
-	response := routesToResponce(routes)
-	if err := json.NewEncoder(w).Encode(&response); err != nil {
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintf(w, "Error: %s", err)
 		return
 	}
+
+	// Send response back to client in the requested format
+	respond(w, req, routes, p.Elevation != nil)
 }
 
-// RoutesToResponse takes a routing.Routes object and condenses it to the most
-// essential data needed in the server response.
-func routesToResponce(routes routing.Routes) (res Responce) {
+// errAvoided is reported when no routes are left after avoiding the requested
+// areas and ways.
+var errAvoided = errors.New("no routes avoid the given areas and ways")
+
+// Fetch returns map data for the area from the source of the planner without
+// the edges that are to be avoided, and all of it, both with elevations if
+// available. If it cannot be fetched or nothing is left to route on the error
+// is reported to the client and ok is false.
+func (p *Planner) fetch(w http.ResponseWriter, r *http.Request, area overpass.Area, avoid routing.Avoid) (graph, all routing.Graph, ok bool) {
+
+	all, err := p.Source.Fetch(r.Context(), area)
+
+	if r.Context().Err() != nil {
+		// client has gone away, there is nobody to respond to
+		return nil, nil, false
+	}
+
+	if err != nil {
+		if errors.Is(err, overpass.ErrRateLimited) {
+			w.Header().Set("Retry-After", "30")
+		}
+		w.WriteHeader(fetchErrorStatus(err))
+		fmt.Fprintf(w, "Error: %s", err)
+		return nil, nil, false
+	}
+
+	if p.Elevation != nil {
+		all.SetElevations(p.Elevation.Elevation)
+	}
+
+	graph = avoid.Apply(all)
+
+	if len(graph) == 0 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintf(w, "Error: %s", errAvoided)
+		return nil, nil, false
+	}
+
+	return graph, all, true
+}
+
+// SearchArea returns the area that map data is needed for to plan routes of
+// the given distance in meters and shape. Routes stay within a disc around the
+// start as far as the shape can reach, or as far as the furthest waypoint,
+// with some margin for the detours forced by the paths.
+func searchArea(lat, lon, distance float64, shape routing.Shape, waypoints []routing.Waypoint) overpass.Area {
+
+	reach := shape.Reach(distance)
+	start := &routing.Node{Lat: lat, Lon: lon}
+
+	for _, wp := range waypoints {
+		reach = math.Max(reach, routing.Haversine(start, &routing.Node{Lat: wp.Lat, Lon: wp.Lon}))
+	}
+
+	return overpass.Around{
+		Lat:    lat,
+		Lon:    lon,
+		Radius: 1.2 * reach,
+	}
+}
+
+// ParseWaypoints parses waypoints given as lat,lon pairs, e.g. "51.27,0.19".
+func parseWaypoints(pairs []string) ([]routing.Waypoint, error) {
+
+	waypoints := []routing.Waypoint{}
+
+	for _, pair := range pairs {
+		parts := strings.Split(pair, ",")
+
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid waypoint %q", pair)
+		}
+
+		lat, err := strconv.ParseFloat(parts[0], 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid waypoint %q", pair)
+		}
+
+		lon, err := strconv.ParseFloat(parts[1], 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid waypoint %q", pair)
+		}
+
+		waypoints = append(waypoints, routing.Waypoint{Lat: lat, Lon: lon})
+	}
+
+	return waypoints, nil
+}
+
+// ParseAvoid parses areas given as lat,lon,lat,lon,... corners of polygons,
+// e.g. "51.27,0.19,51.28,0.19,51.28,0.2", and ways given as comma separated
+// OSM ids, e.g. "4029133,4029134".
+func parseAvoid(areas []string, ways string) (routing.Avoid, error) {
+
+	avoid := routing.Avoid{Ways: make(map[int]bool)}
+
+	for _, area := range areas {
+		values := strings.Split(area, ",")
+
+		if len(values) < 6 || len(values)%2 != 0 {
+			return avoid, fmt.Errorf("invalid area %q", area)
+		}
+
+		polygon := overpass.Polygon{}
+
+		for i := 0; i < len(values); i += 2 {
+			lat, err := strconv.ParseFloat(values[i], 64)
+
+			if err != nil {
+				return avoid, fmt.Errorf("invalid area %q", area)
+			}
+
+			lon, err := strconv.ParseFloat(values[i+1], 64)
 
-	for _, val := range routes {
-		route := Route{Path: []CoordPair{}, Distance: val.Length}
+			if err != nil {
+				return avoid, fmt.Errorf("invalid area %q", area)
+			}
+
+			polygon = append(polygon, overpass.Point{Lat: lat, Lon: lon})
+		}
+
+		avoid.Areas = append(avoid.Areas, polygon)
+	}
+
+	if ways == "" {
+		return avoid, nil
+	}
+
+	for _, way := range strings.Split(ways, ",") {
+		id, err := strconv.Atoi(way)
+
+		if err != nil {
+			return avoid, fmt.Errorf("invalid way id %q", way)
+		}
+
+		avoid.Ways[id] = true
+	}
+
+	return avoid, nil
+}
+
+// CheckWaypoints returns an error if a loop of the distance in meters and
+// shape cannot pass through the waypoints.
+func checkWaypoints(lat, lon, distance float64, shape routing.Shape, waypoints []routing.Waypoint, avoid routing.Avoid) error {
+
+	if len(waypoints) == 0 {
+		return nil
+	}
+
+	if shape != routing.Loop {
+		return errors.New("waypoints are only supported for loops")
+	}
+
+	if len(waypoints) > maxWaypoints {
+		return errors.New("too many waypoints")
+	}
+
+	start := &routing.Node{Lat: lat, Lon: lon}
+
+	for _, wp := range waypoints {
+		// a loop has to get there and back
+		if routing.Haversine(start, &routing.Node{Lat: wp.Lat, Lon: wp.Lon}) > distance/2 {
+			return fmt.Errorf("waypoint %v,%v is too far from the start", wp.Lat, wp.Lon)
+		}
+
+		if avoid.Contains(wp.Lat, wp.Lon) {
+			return fmt.Errorf("waypoint %v,%v is in an avoided area", wp.Lat, wp.Lon)
+		}
+	}
+
+	return nil
+}
+
+// PathArea returns the area that map data is needed for to plan routes
+// between two points the given distance in meters apart. It is a disc around
+// the midpoint, with a margin so that alternatives and paths that have to
+// detour around obstacles are found too.
+func pathArea(start, end *routing.Node, distance float64) overpass.Area {
+	return overpass.Around{
+		Lat:    (start.Lat + end.Lat) / 2,
+		Lon:    (start.Lon + end.Lon) / 2,
+		Radius: distance/2 + math.Max(500, 0.3*distance),
+	}
+}
+
+// FetchErrorStatus maps an error returned by a DataSource to the status code
+// reported to the client.
+func fetchErrorStatus(err error) int {
+	var remarkErr *overpass.RemarkError
+
+	switch {
+	case errors.Is(err, overpass.ErrServerTimeout),
+		errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, overpass.ErrMalformedResponse),
+		errors.As(err, &remarkErr):
+		return http.StatusBadGateway
+	case errors.Is(err, ErrNoData):
+		return http.StatusNotFound
+	}
+
+	return http.StatusServiceUnavailable
+}
+
+// ParseWeights parses score weights given as comma separated name:weight
+// pairs, e.g. "turns:20,repeats:5000".
+func parseWeights(s string) (map[string]float64, error) {
+
+	weights := make(map[string]float64)
+
+	if s == "" {
+		return weights, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		parts := strings.Split(pair, ":")
+
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid weight %q", pair)
+		}
+
+		weight, err := strconv.ParseFloat(parts[1], 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight %q", pair)
+		}
+
+		weights[parts[0]] = weight
+	}
+
+	return weights, nil
+}
+
+// ElevationProfile returns the elevation profile of a route.
+func elevationProfile(route routing.Route) *Elevation {
+
+	profile := &Elevation{
+		Heights:   make([]*float64, len(route.Path)),
+		Distances: route.Distances(),
+		Ascent:    route.Ascent,
+		Descent:   route.Descent,
+		MaxGrade:  route.MaxGrade(),
+	}
+
+	for i, node := range route.Path {
+		if !math.IsNaN(node.Elevation) {
+			height := node.Elevation
+			profile.Heights[i] = &height
+		}
+	}
+
+	return profile
+}
+
+// RoutesToResponse takes the routes of an output and condenses them to the
+// most essential data needed in the server response. Scores, elevation
+// profiles and directions are only included when the output asks for them.
+func routesToResponce(out output) (res Responce) {
+
+	for _, val := range out.Routes {
+		route := Route{
+			Path:       []CoordPair{},
+			Distance:   val.Length,
+			Boundaries: val.Boundaries,
+		}
+		if out.Elevation {
+			route.Elevation = elevationProfile(val)
+		}
+		if out.Explain {
+			route.Score, route.Breakdown = val.Score, val.Breakdown
+		}
+		if out.Directions {
+			route.Directions = directions(val, out.Locale)
+		}
 		for _, node := range val.Path {
 			route.Path = append(route.Path, CoordPair{node.Lat, node.Lon})
 		}
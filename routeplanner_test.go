@@ -0,0 +1,547 @@
+package routeplanner
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yurachistic1/routeplanner-backend/overpass"
+	"github.com/yurachistic1/routeplanner-backend/routing"
+)
+
+type failingSource struct {
+	err error
+}
+
+func (s failingSource) Fetch(ctx context.Context, area overpass.Area) (routing.Graph, error) {
+	return nil, s.err
+}
+
+// slope rises by 10m for every row of the test grid going north.
+type slope struct{}
+
+func (slope) Elevation(lat, lon float64) (float64, error) {
+	return (lat - 51.266) * 10000, nil
+}
+
+func serve(p *Planner, query string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?"+query, nil))
+	return w
+}
+
+func TestPlannerRoutes(t *testing.T) {
+
+	p := &Planner{Source: &FileSource{Path: "testdata/grid.json"}}
+
+	for _, query := range []string{
+		"lat=51.27&lon=0.19&distance=1",
+		"lat=51.27&lon=0.19&distance=1&profile=paved",
+		"lat=51.27&lon=0.19&distance=1&weights=turns:0,repeats:500&explain=true",
+		"lat=51.27&lon=0.19&distance=1&shape=outandback",
+		"lat=51.27&lon=0.19&distance=1&shape=lollipop",
+		"lat=51.27&lon=0.19&distance=2&shape=multiloop&loops=2",
+		"lat=51.27&lon=0.19&distance=2&via=51.272,0.192&via=51.268,0.187",
+		"lat=51.27&lon=0.19&distance=2&via=51.272,0.192&via=51.268,0.187&ordered=true",
+	} {
+		w := serve(p, query)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status == %d, want %d: %s", query, w.Code, http.StatusOK, w.Body)
+		}
+
+		var res Responce
+
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatalf("%s: response is not valid JSON: %v", query, err)
+		}
+
+		if len(res) == 0 {
+			t.Fatalf("%s: no routes returned", query)
+		}
+
+		for _, route := range res {
+			if route.Path[0] != route.Path[len(route.Path)-1] {
+				t.Errorf("%s: route %v does not end at the start", query, route.Path)
+			}
+		}
+	}
+}
+
+func TestPlannerPaths(t *testing.T) {
+
+	p := &Planner{Source: &FileSource{Path: "testdata/grid.json"}}
+
+	query := "lat=51.2665&lon=0.1845&endlat=51.2715&endlon=0.1905&alternatives=2&explain=true"
+
+	w := serve(p, query)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s: status == %d, want %d: %s", query, w.Code, http.StatusOK, w.Body)
+	}
+
+	var res Responce
+
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("%s: response is not valid JSON: %v", query, err)
+	}
+
+	if len(res) == 0 || len(res) > 3 {
+		t.Fatalf("%s: %d routes returned, want 1 to 3", query, len(res))
+	}
+
+	for _, route := range res {
+		if route.Path[0] != res[0].Path[0] || route.Path[len(route.Path)-1] != res[0].Path[len(res[0].Path)-1] {
+			t.Errorf("%s: route %v does not connect the same points", query, route.Path)
+		}
+
+		if route.Distance < res[0].Distance {
+			t.Errorf("%s: alternative is shorter than the first route", query)
+		}
+	}
+}
+
+func TestPlannerAvoid(t *testing.T) {
+
+	p := &Planner{Source: &FileSource{Path: "testdata/grid.json"}}
+
+	// the east of the grid and Row 3
+	query := "lat=51.27&lon=0.19&distance=1&avoid=51.265,0.1925,51.275,0.1925,51.275,0.198,51.265,0.198&avoidways=1003"
+
+	w := serve(p, query)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s: status == %d, want %d: %s", query, w.Code, http.StatusOK, w.Body)
+	}
+
+	var res Responce
+	json.Unmarshal(w.Body.Bytes(), &res)
+
+	if len(res) == 0 {
+		t.Fatalf("%s: no routes returned", query)
+	}
+
+	for _, route := range res {
+		for i, point := range route.Path {
+			if point[1] > 0.1925 {
+				t.Errorf("%s: route passes %v in the avoided area", query, point)
+			}
+
+			if i > 0 && point[0] == 51.269 && route.Path[i-1][0] == 51.269 {
+				t.Errorf("%s: route follows the avoided way at %v", query, point)
+			}
+		}
+	}
+}
+
+func TestPlannerLoopAvoided(t *testing.T) {
+
+	p := &Planner{Source: &FileSource{Path: "testdata/grid.json"}}
+
+	cases := []struct {
+		query string
+		want  error
+	}{
+		// there are not enough separate loops with or without the way
+		{"lat=51.27&lon=0.19&distance=1&shape=multiloop&loops=4&avoidways=1000", errNoRoutes},
+		// the loops are only lost to the avoided columns
+		{"lat=51.2665&lon=0.19&distance=1&avoidways=1009,1010,1011,1012,1013,1014,1015,1016,1017", errAvoided},
+	}
+
+	for _, c := range cases {
+		w := serve(p, c.query)
+
+		if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), c.want.Error()) {
+			t.Errorf("%s: %d %s, want %d %s", c.query, w.Code, w.Body, http.StatusUnprocessableEntity, c.want)
+		}
+	}
+}
+
+func TestPlannerPathAvoided(t *testing.T) {
+
+	// two squares 700m apart that are not connected, the first one with a
+	// diagonal
+	path := filepath.Join(t.TempDir(), "squares.json")
+	data := `{"elements": [
+		{"type": "way", "id": 1, "nodes": [1, 2, 3, 4, 1], "tags": {"highway": "footway"}},
+		{"type": "way", "id": 2, "nodes": [1, 3], "tags": {"highway": "footway"}},
+		{"type": "way", "id": 3, "nodes": [5, 6, 7, 8, 5], "tags": {"highway": "footway"}},
+		{"type": "node", "id": 1, "lat": 51.500, "lon": -0.100},
+		{"type": "node", "id": 2, "lat": 51.501, "lon": -0.100},
+		{"type": "node", "id": 3, "lat": 51.501, "lon": -0.099},
+		{"type": "node", "id": 4, "lat": 51.500, "lon": -0.099},
+		{"type": "node", "id": 5, "lat": 51.500, "lon": -0.090},
+		{"type": "node", "id": 6, "lat": 51.501, "lon": -0.090},
+		{"type": "node", "id": 7, "lat": 51.501, "lon": -0.089},
+		{"type": "node", "id": 8, "lat": 51.500, "lon": -0.089}
+	]}`
+
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	squares := &Planner{Source: &FileSource{Path: path}}
+	grid := &Planner{Source: &FileSource{Path: "testdata/grid.json"}}
+
+	cases := []struct {
+		planner *Planner
+		query   string
+		want    error
+	}{
+		// the avoided diagonal is not why there is no route
+		{squares, "lat=51.500&lon=-0.100&endlat=51.501&endlon=-0.089&avoidways=2", routing.ErrUnreachable},
+		// a strip across the grid cuts it in two
+		{grid, "lat=51.2665&lon=0.1845&endlat=51.2715&endlon=0.1905&avoid=51.26,0.1876,51.28,0.1876,51.28,0.1884,51.26,0.1884", errAvoided},
+	}
+
+	for _, c := range cases {
+		w := serve(c.planner, c.query)
+
+		if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), c.want.Error()) {
+			t.Errorf("%s: %d %s, want %d %s", c.query, w.Code, w.Body, http.StatusUnprocessableEntity, c.want)
+		}
+	}
+}
+
+func TestPlannerElevation(t *testing.T) {
+
+	p := &Planner{Source: &FileSource{Path: "testdata/grid.json"}, Elevation: slope{}}
+
+	climbs := map[string]float64{}
+
+	for _, hills := range []string{"flat", "hilly"} {
+		query := "lat=51.27&lon=0.19&distance=2&hills=" + hills
+
+		var res Responce
+		json.Unmarshal(serve(p, query).Body.Bytes(), &res)
+
+		if len(res) == 0 {
+			t.Fatalf("%s: no routes returned", query)
+		}
+
+		for _, route := range res {
+			e := route.Elevation
+
+			if e == nil || len(e.Heights) != len(route.Path) || len(e.Distances) != len(route.Path) {
+				t.Fatalf("%s: elevation profile %+v does not match the path", query, e)
+			}
+
+			// loops end where they started
+			if math.Abs(e.Ascent-e.Descent) > 1e-6 {
+				t.Errorf("%s: ascent %v and descent %v differ", query, e.Ascent, e.Descent)
+			}
+
+			if math.Abs(e.Distances[len(e.Distances)-1]-route.Distance) > 1e-6 {
+				t.Errorf("%s: distances end at %v, route is %v long", query, e.Distances[len(e.Distances)-1], route.Distance)
+			}
+
+			// the slope is about 9%
+			if e.MaxGrade < 8 || e.MaxGrade > 10 {
+				t.Errorf("%s: max grade == %v, want about 9", query, e.MaxGrade)
+			}
+
+			for i, point := range route.Path {
+				if want := (point[0] - 51.266) * 10000; e.Heights[i] == nil || math.Abs(*e.Heights[i]-want) > 1e-6 {
+					t.Errorf("%s: height at %v == %v, want %v", query, point, e.Heights[i], want)
+					break
+				}
+			}
+		}
+
+		climbs[hills] = res[0].Elevation.Ascent
+	}
+
+	if climbs["flat"] > climbs["hilly"] {
+		t.Errorf("flat route climbs %vm, more than the hilly one with %vm", climbs["flat"], climbs["hilly"])
+	}
+}
+
+// Counters return the number of routes in a response body of some format.
+func gpxTracks(body []byte) (int, error) {
+	var doc gpx
+	err := xml.Unmarshal(body, &doc)
+	return len(doc.Tracks), err
+}
+
+func tcxCourses(body []byte) (int, error) {
+	var doc tcx
+	err := xml.Unmarshal(body, &doc)
+	return len(doc.Courses), err
+}
+
+func fitCourses(body []byte) (int, error) {
+	messages, err := decodeFIT(body)
+	n := 0
+	for _, m := range messages {
+		if m.global == fitCourse {
+			n++
+		}
+	}
+	return n, err
+}
+
+func geoJSONFeatures(body []byte) (int, error) {
+	var doc featureCollection
+	err := json.Unmarshal(body, &doc)
+	return len(doc.Features), err
+}
+
+func TestPlannerFormats(t *testing.T) {
+
+	p := &Planner{Source: &FileSource{Path: "testdata/grid.json"}}
+
+	cases := []struct {
+		query       string
+		contentType string
+		filename    string
+		count       func(body []byte) (int, error)
+		routes      int
+	}{
+		{"lat=51.27&lon=0.19&distance=1&format=gpx", "application/gpx+xml", "routes.gpx", gpxTracks, -1},
+		{"lat=51.27&lon=0.19&distance=1&format=gpx&route=0", "application/gpx+xml", "route-1.gpx", gpxTracks, 1},
+		{"lat=51.2665&lon=0.1845&endlat=51.2735&endlon=0.1845&format=gpx&route=1", "application/gpx+xml", "route-2.gpx", gpxTracks, 1},
+		{"lat=51.27&lon=0.19&distance=1&format=geojson", "application/geo+json", "", geoJSONFeatures, -1},
+		{"lat=51.27&lon=0.19&distance=1&format=geojson&route=2", "application/geo+json", "", geoJSONFeatures, 1},
+		{"lat=51.27&lon=0.19&distance=1&format=tcx&route=1", "application/vnd.garmin.tcx+xml", "route-2.tcx", tcxCourses, 1},
+		{"lat=51.27&lon=0.19&distance=1&format=fit", "application/vnd.ant.fit", "route-1.fit", fitCourses, 1},
+		{"lat=51.2665&lon=0.1845&endlat=51.2735&endlon=0.1905&format=fit&route=0", "application/vnd.ant.fit", "route-1.fit", fitCourses, 1},
+	}
+
+	for _, c := range cases {
+		w := serve(p, c.query)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status == %d, want %d: %s", c.query, w.Code, http.StatusOK, w.Body)
+		}
+
+		if got := w.Header().Get("Content-Type"); got != c.contentType {
+			t.Errorf("%s: Content-Type == %q, want %q", c.query, got, c.contentType)
+		}
+
+		got := w.Header().Get("Content-Disposition")
+		if c.filename == "" && got != "" || !strings.Contains(got, c.filename) {
+			t.Errorf("%s: Content-Disposition == %q, want %q", c.query, got, c.filename)
+		}
+
+		n, err := c.count(w.Body.Bytes())
+		if err != nil {
+			t.Fatalf("%s: invalid response: %v", c.query, err)
+		}
+
+		if n == 0 || c.routes > 0 && n != c.routes {
+			t.Errorf("%s: %d routes returned, want %d", c.query, n, c.routes)
+		}
+	}
+}
+
+func TestPlannerPolyline(t *testing.T) {
+
+	p := &Planner{Source: &FileSource{Path: "testdata/grid.json"}}
+
+	for _, precision := range []int{5, 6} {
+		query := fmt.Sprintf("lat=51.2665&lon=0.1845&endlat=51.2715&endlon=0.1905&format=polyline&precision=%d", precision)
+
+		var plain, encoded Responce
+		json.Unmarshal(serve(p, "lat=51.2665&lon=0.1845&endlat=51.2715&endlon=0.1905").Body.Bytes(), &plain)
+		json.Unmarshal(serve(p, query).Body.Bytes(), &encoded)
+
+		if len(encoded) == 0 || len(encoded) != len(plain) {
+			t.Fatalf("%s: %d routes returned, want %d", query, len(encoded), len(plain))
+		}
+
+		for i, route := range encoded {
+			if route.Path != nil || route.Polyline == "" {
+				t.Errorf("%s: route %d has path %v and polyline %q", query, i, route.Path, route.Polyline)
+			}
+
+			path, err := decodePolyline(route.Polyline, precision)
+
+			if err != nil || !reflect.DeepEqual(path, plain[i].Path) {
+				t.Errorf("%s: polyline decodes to %v, %v, want %v", query, path, err, plain[i].Path)
+			}
+		}
+	}
+}
+
+func TestPlannerDirections(t *testing.T) {
+
+	p := &Planner{Source: &FileSource{Path: "testdata/grid.json"}}
+
+	for _, query := range []string{
+		"lat=51.2665&lon=0.1845&endlat=51.2715&endlon=0.1905&directions=true",
+		"lat=51.27&lon=0.19&distance=1&directions=true",
+	} {
+		var res Responce
+		json.Unmarshal(serve(p, query).Body.Bytes(), &res)
+
+		if len(res) == 0 {
+			t.Fatalf("%s: no routes returned", query)
+		}
+
+		for _, route := range res {
+			d := route.Directions
+
+			if len(d) < 2 || d[0].Action != "depart" || d[len(d)-1].Action != "arrive" || d[len(d)-1].Index != len(route.Path)-1 {
+				t.Fatalf("%s: directions %+v do not lead along the path", query, d)
+			}
+
+			total := 0.0
+
+			for i, step := range d {
+				total += step.Distance
+
+				if step.Text == "" || i > 0 && step.Index <= d[i-1].Index {
+					t.Errorf("%s: direction %+v out of order or without text", query, step)
+				}
+			}
+
+			if math.Abs(total-route.Distance) > 1e-6 {
+				t.Errorf("%s: directions cover %v, route is %v long", query, total, route.Distance)
+			}
+		}
+	}
+
+	var res Responce
+	json.Unmarshal(serve(p, "lat=51.27&lon=0.19&distance=1").Body.Bytes(), &res)
+
+	if len(res) == 0 || res[0].Directions != nil {
+		t.Errorf("directions included without being requested")
+	}
+}
+
+func TestPlannerLocale(t *testing.T) {
+
+	p := &Planner{Source: &FileSource{Path: "testdata/grid.json"}}
+
+	cases := []struct {
+		query, acceptLanguage string
+		want                  string
+	}{
+		{"directions=true", "", "en"},
+		{"directions=true&lang=de", "fr", "de"},
+		{"directions=true", "ru;q=0.9, fr", "fr"},
+		{"directions=true&lang=xx", "", "en"},
+	}
+
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/?lat=51.2665&lon=0.1845&endlat=51.2715&endlon=0.1905&"+c.query, nil)
+		r.Header.Set("Accept-Language", c.acceptLanguage)
+
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, r)
+
+		if got := w.Header().Get("Content-Language"); got != c.want {
+			t.Errorf("%s with %q: Content-Language == %q, want %q", c.query, c.acceptLanguage, got, c.want)
+		}
+
+		var res Responce
+		json.Unmarshal(w.Body.Bytes(), &res)
+
+		if len(res) == 0 || len(res[0].Directions) == 0 {
+			t.Fatalf("%s: no directions returned", c.query)
+		}
+
+		last := res[0].Directions[len(res[0].Directions)-1]
+		if want := locales[strings.ToLower(c.want)].text("arrive", nil); last.Text != want {
+			t.Errorf("%s with %q: last direction == %q, want %q", c.query, c.acceptLanguage, last.Text, want)
+		}
+	}
+}
+
+func TestPlannerErrors(t *testing.T) {
+
+	cases := []struct {
+		source DataSource
+		query  string
+		want   int
+	}{
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&distance=20", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&distance=5&profile=hilly", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&distance=5&weights=hills:2", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&distance=5&weights=turns", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&distance=5&shape=star", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&distance=5&loops=2", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&distance=5&shape=multiloop&loops=9", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&distance=1&shape=multiloop&loops=4", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&distance=2&via=51.272", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&distance=2&via=51.29,0.19", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&distance=2&via=51.272,0.192&shape=outandback", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&distance=1&via=51.266,0.184&via=51.274,0.194", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&distance=1&avoid=51.27,0.19", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&distance=1&avoidways=row", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&distance=1&avoid=51.26,0.18,51.28,0.18,51.28,0.2,51.26,0.2", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.2665&lon=0.19&distance=1&avoidways=1009,1010,1011,1012,1013,1014,1015,1016,1017", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.2665&lon=0.1845&endlat=51.2735&endlon=0.1845&avoid=51.2695,0.18,51.2705,0.18,51.2705,0.2,51.2695,0.2", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&distance=1&hills=steep", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&distance=1&hills=hilly", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&endlat=51.27", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&endlat=51.5&endlon=0.19", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&endlat=51.271&endlon=0.19&alternatives=9", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&endlat=51.271&endlon=0.19&weights=repeats:1", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&endlat=51.271&endlon=0.19&distance=2", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&endlat=51.271&endlon=0.19&shape=lollipop", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&endlat=51.271&endlon=0.19&loops=2", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&endlat=51.271&endlon=0.19&via=51.272,0.192", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&endlat=51.271&endlon=0.19&ordered=true", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&distance=1&format=kml", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&distance=1&format=polyline&precision=7", http.StatusUnprocessableEntity},
+		{&FileSource{Path: "testdata/grid.json"}, "lat=51.27&lon=0.19&distance=1&format=gpx&route=99", http.StatusUnprocessableEntity},
+		{failingSource{&overpass.StatusError{StatusCode: 429}}, "lat=51.27&lon=0.19&distance=5", http.StatusServiceUnavailable},
+		{failingSource{&overpass.StatusError{StatusCode: 504}}, "lat=51.27&lon=0.19&distance=5", http.StatusGatewayTimeout},
+		{failingSource{overpass.ErrMalformedResponse}, "lat=51.27&lon=0.19&distance=5", http.StatusBadGateway},
+		{failingSource{ErrNoData}, "lat=51.27&lon=0.19&distance=5", http.StatusNotFound},
+	}
+
+	for _, c := range cases {
+		w := serve(&Planner{Source: c.source}, c.query)
+
+		if w.Code != c.want {
+			t.Errorf("%s with %T: status == %d, want %d", c.query, c.source, w.Code, c.want)
+		}
+	}
+}
+
+func TestPlannerExplain(t *testing.T) {
+
+	p := &Planner{Source: &FileSource{Path: "testdata/grid.json"}}
+
+	for _, explain := range []bool{false, true} {
+		query := "lat=51.27&lon=0.19&distance=1"
+		if explain {
+			query += "&explain=true"
+		}
+
+		var res Responce
+		json.Unmarshal(serve(p, query).Body.Bytes(), &res)
+
+		if len(res) == 0 {
+			t.Fatalf("%s: no routes returned", query)
+		}
+
+		if res[0].Elevation != nil {
+			t.Errorf("%s: elevation profile included without elevation data", query)
+		}
+
+		_, ok := res[0].Breakdown["turns"]
+
+		if ok != explain {
+			t.Errorf("%s: breakdown == %v", query, res[0].Breakdown)
+		}
+
+		for i := 1; explain && i < len(res); i++ {
+			if res[i].Score < res[i-1].Score {
+				t.Errorf("%s: routes are not ordered by score", query)
+			}
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package routing
 
 import (
+	"container/heap"
+	"errors"
 	"math"
 )
 
@@ -21,74 +23,127 @@ func reconstructPath(cameFrom map[Id]Id, current Id) (totalPath []Id) {
 	return
 }
 
-// A* search is used to complete the routes into a cycle and is not an optimal implementation
-// lacking the use of a priority queue for the open set. For the purposes of the
-// project it is fast enough.
-func aStar(path []*Node, graph Graph) []Id {
+// ErrUnreachable is returned when A* cannot find a path to the goal, either
+// because there is none or because the search budget ran out.
+var ErrUnreachable = errors.New("goal is unreachable")
 
-	goal := path[0]
-	start := path[len(path)-1]
-	openSet := map[Id]struct{}{start.Id: {}}
+// Maximum number of nodes expanded by a single A* search unless set in Options.
+const defaultSearchBudget = 100000
+
+// CostFunc returns the cost of travelling along an edge. A* uses straight line
+// distance as its heuristic, so costs lower than the length of the edge can
+// lead to paths that are not optimal.
+type CostFunc func(e Edge) float64
+
+// EdgeCost is the cost function used by A* internally, it also receives ids
+// of the nodes the edge leads from and to.
+type edgeCost func(from, to Id, e Edge) float64
+
+func (cost CostFunc) edgeCost() edgeCost {
+	return func(_, _ Id, e Edge) float64 {
+		return cost(e)
+	}
+}
+
+// DistanceCost is a CostFunc minimising the length of the path.
+func DistanceCost(e Edge) float64 {
+	return e.Distance
+}
+
+// A* search returns ids of nodes on the cheapest path from start to goal,
+// both included. The open set is a priority queue ordered by estimated total
+// cost. At most budget nodes are expanded before giving up.
+func aStar(start, goal *Node, graph Graph, cost edgeCost, budget int) ([]Id, error) {
+
+	openSet := &priorityQueue{{start.Id, Haversine(start, goal)}}
+
+	closed := make(map[Id]bool)
 
 	cameFrom := make(map[Id]Id)
 
 	gScore := map[Id]float64{start.Id: 0}
 
-	fScore := map[Id]float64{start.Id: Haversine(start, goal)}
+	for expanded := 0; openSet.Len() != 0 && expanded < budget; {
 
-	for len(openSet) != 0 {
-		min := math.Inf(1)
+		current := heap.Pop(openSet).(queueItem).id
 
-		var minNode Id
-
-		for node, score := range fScore {
-			_, ok := openSet[node]
-			if score < min && ok {
-				minNode = node
-				min = score
-			}
+		if current == goal.Id {
+			return reconstructPath(cameFrom, current), nil
 		}
 
-		current := minNode
-		if current == goal.Id {
-			return reconstructPath(cameFrom, current)
+		// stale entries are left in the queue when a node is re-queued with
+		// a better score
+		if closed[current] {
+			continue
 		}
 
-		delete(openSet, current)
+		closed[current] = true
+		expanded++
 
-		neighbours := graph[current].Adjacent
+		for _, id := range graph[current].Adjacent {
+			node, ok := graph[id]
 
-		for _, id := range neighbours {
-			node := graph[id]
-			d := graph[current].Edges[id].Distance
+			if !ok || closed[id] {
+				continue
+			}
+
+			tentative_gScore := gScore[current] + cost(current, id, graph[current].Edges[id])
 
-			tentative_gScore := getWithDefault(gScore, current, math.Inf(1)) + d
 			if tentative_gScore < getWithDefault(gScore, id, math.Inf(1)) {
 				cameFrom[id] = current
 				gScore[id] = tentative_gScore
 
 				h := Haversine(node, goal)
-				fScore[id] = getWithDefault(gScore, id, math.Inf(1)) + h
-
-				openSet[id] = struct{}{}
+				heap.Push(openSet, queueItem{id, tentative_gScore + h})
 			}
 		}
-
 	}
 
-	return []Id{}
+	return nil, ErrUnreachable
+}
+
+type queueItem struct {
+	id Id
+	f  float64
+}
+
+// PriorityQueue implements heap.Interface with the lowest f on top.
+type priorityQueue []queueItem
+
+func (q priorityQueue) Len() int            { return len(q) }
+func (q priorityQueue) Less(i, j int) bool  { return q[i].f < q[j].f }
+func (q priorityQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *priorityQueue) Push(x interface{}) { *q = append(*q, x.(queueItem)) }
+
+func (q *priorityQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
 }
 
 // CompleteRoute takes an incomplete cycle and completes it using A* as
-// well as adjusting values such as length.
-func completeRoute(route Route, graph Graph) Route {
-	lastStretch := aStar(route.Path, graph)
+// well as adjusting values such as length and profile penalty. The cycle is
+// closed at the anchor of the route and the path leading to it is retraced.
+func completeRoute(route Route, graph Graph, opts Options) (Route, error) {
+	profile := opts.Profile
+	anchor := route.anchor
+
+	lastStretch, err := aStar(route.Path[len(route.Path)-1], route.Path[anchor], graph, opts.cost().edgeCost(), opts.budget())
+
+	if err != nil {
+		return route, err
+	}
 
 	i := 0
 	node := lastStretch[i]
 
-	for node == route.Path[len(route.Path)-1].Id {
-		route.Length -= graph[node].Edges[route.Path[len(route.Path)-2].Id].Distance
+	for node == route.Path[len(route.Path)-1].Id && len(route.Path) > anchor+1 {
+		edge := route.Path[len(route.Path)-2].Edges[node]
+		route.Length -= edge.Distance
+		route.Penalty -= profile.Penalty(edge)
+		route.Ascent -= edge.Gain
+		route.Descent -= edge.Loss
 		route.Path = route.Path[:len(route.Path)-1]
 		i++
 		if i == len(lastStretch) {
@@ -98,12 +153,29 @@ func completeRoute(route Route, graph Graph) Route {
 	}
 
 	for i = i - 1; i < len(lastStretch); i++ {
-		route.Length += route.Path[len(route.Path)-1].Edges[lastStretch[i]].Distance
+		edge := route.Path[len(route.Path)-1].Edges[lastStretch[i]]
+		route.Length += edge.Distance
+		route.Penalty += profile.Penalty(edge)
+		route.Ascent += edge.Gain
+		route.Descent += edge.Loss
 		route.Path = append(route.Path, graph[lastStretch[i]])
 	}
 
-	return route
+	for i = anchor - 1; i >= 0; i-- {
+		edge := route.Path[len(route.Path)-1].Edges[route.Path[i].Id]
+		route.Length += edge.Distance
+		route.Penalty += profile.Penalty(edge)
+		route.Ascent += edge.Gain
+		route.Descent += edge.Loss
+		route.Path = append(route.Path, route.Path[i])
+		route.Visited[route.Path[i].Id] += 1
+		route.RepeatVisits++
+		route.IntendedRepeats++
+	}
+
+	route.anchor = 0
 
+	return route, nil
 }
 
 // GetWithDefault returns a value from a map if a key is present or it
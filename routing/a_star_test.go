@@ -0,0 +1,105 @@
+package routing
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testGraph returns a graph of nodes placed on a grid roughly 100m apart
+// connected by the given edges.
+func testGraph(coords map[Id][2]float64, edges [][2]Id, ways map[[2]Id]*Way) Graph {
+
+	g := make(Graph)
+
+	for id, c := range coords {
+		g[id] = &Node{Id: id, Lat: 51.5 + c[0]*0.0009, Lon: -0.1 + c[1]*0.00145, Edges: map[Id]Edge{}}
+	}
+
+	for _, e := range edges {
+		a, b := g[e[0]], g[e[1]]
+		a.Adjacent = append(a.Adjacent, b.Id)
+		b.Adjacent = append(b.Adjacent, a.Id)
+		a.Edges[b.Id] = Edge{Distance: Haversine(a, b), Bearing: Bearing(a, b), Way: ways[e]}
+		b.Edges[a.Id] = Edge{Distance: Haversine(a, b), Bearing: Bearing(b, a), Way: ways[e]}
+	}
+
+	return g
+}
+
+func TestAStar(t *testing.T) {
+
+	//  1 - 2 - 3
+	//  |       |
+	//  4 - 5 - 6     7 - 8
+	g := testGraph(
+		map[Id][2]float64{1: {1, 0}, 2: {1, 1}, 3: {1, 2}, 4: {0, 0}, 5: {0, 1}, 6: {0, 2}, 7: {0, 4}, 8: {0, 5}},
+		[][2]Id{{1, 2}, {2, 3}, {1, 4}, {4, 5}, {5, 6}, {3, 6}, {7, 8}},
+		map[[2]Id]*Way{{2, 3}: {Highway: "steps"}},
+	)
+
+	cases := []struct {
+		start, goal Id
+		cost        CostFunc
+		budget      int
+		want        []Id
+		err         error
+	}{
+		{1, 3, DistanceCost, 100, []Id{1, 2, 3}, nil},
+		{1, 3, NoSteps.Cost, 100, []Id{1, 4, 5, 6, 3}, nil},
+		{5, 5, DistanceCost, 100, []Id{5}, nil},
+		{1, 8, DistanceCost, 100, nil, ErrUnreachable},
+		{1, 3, NoSteps.Cost, 2, nil, ErrUnreachable},
+	}
+
+	for _, c := range cases {
+		result, err := aStar(g[c.start], g[c.goal], g, c.cost.edgeCost(), c.budget)
+
+		if err != c.err || !reflect.DeepEqual(result, c.want) {
+			t.Errorf("aStar(%v, %v) == %v, %v, want %v, %v",
+				c.start, c.goal, result, err, c.want, c.err)
+		}
+	}
+}
+
+func TestCompleteRoute(t *testing.T) {
+
+	g := testGraph(
+		map[Id][2]float64{1: {1, 0}, 2: {1, 1}, 3: {1, 2}, 4: {0, 0}, 5: {0, 1}, 6: {0, 2}, 7: {0, 4}, 8: {0, 5}},
+		[][2]Id{{1, 2}, {2, 3}, {1, 4}, {4, 5}, {5, 6}, {3, 6}, {7, 8}},
+		map[[2]Id]*Way{{2, 3}: {Highway: "steps"}},
+	)
+
+	route := Route{Path: []*Node{g[1], g[2], g[3], g[6]}}
+	route.Length = Haversine(g[1], g[2]) + Haversine(g[2], g[3]) + Haversine(g[3], g[6])
+
+	route, err := completeRoute(route, g, Options{Profile: NoSteps})
+
+	ids := []Id{}
+	for _, n := range route.Path {
+		ids = append(ids, n.Id)
+	}
+
+	if err != nil || !reflect.DeepEqual(ids, []Id{1, 2, 3, 6, 5, 4, 1}) {
+		t.Errorf("completeRoute() == %v, %v, want [1 2 3 6 5 4 1]", ids, err)
+	}
+
+	if _, err := completeRoute(Route{Path: []*Node{g[7], g[8], g[7], g[1]}}, g, Options{}); err != ErrUnreachable {
+		t.Errorf("completeRoute() error == %v, want %v", err, ErrUnreachable)
+	}
+}
+
+func TestCompleteRouteRetrace(t *testing.T) {
+
+	g := testGraph(
+		map[Id][2]float64{1: {0, 0}, 2: {0, 1}, 3: {0, 2}},
+		[][2]Id{{1, 2}, {2, 3}},
+		nil,
+	)
+
+	// the only way back is the way out
+	route, err := completeRoute(Route{Path: []*Node{g[1], g[2], g[3]}}, g, Options{})
+
+	if err != nil || route.Path[0] != g[1] || route.Path[len(route.Path)-1] != g[1] {
+		t.Errorf("completeRoute() == %v, %v, want a route back to 1", route.Path, err)
+	}
+}
@@ -0,0 +1,54 @@
+package routing
+
+import "github.com/yurachistic1/routeplanner-backend/overpass"
+
+// Avoid describes parts of the map that routes must not use.
+type Avoid struct {
+	// Areas that routes must not enter or cross.
+	Areas []overpass.Polygon
+
+	// Ways by OSM id that routes must not use.
+	Ways map[int]bool
+}
+
+// Empty reports whether there is nothing to avoid.
+func (a Avoid) Empty() bool {
+	return len(a.Areas) == 0 && len(a.Ways) == 0
+}
+
+// Contains reports whether the location is within any of the areas.
+func (a Avoid) Contains(lat, lon float64) bool {
+	for _, area := range a.Areas {
+		if area.Contains(lat, lon) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply returns a copy of the graph without the edges that are to be avoided,
+// see Graph.Prune.
+func (a Avoid) Apply(graph Graph) Graph {
+	if a.Empty() {
+		return graph
+	}
+	return graph.Prune(a.avoids)
+}
+
+// Avoids reports whether an edge is on an avoided way or enters or crosses
+// an avoided area.
+func (a Avoid) avoids(from, to *Node, e Edge) bool {
+
+	if a.Ways[e.WayId] {
+		return true
+	}
+
+	for _, area := range a.Areas {
+		if area.Contains(from.Lat, from.Lon) || area.Contains(to.Lat, to.Lon) ||
+			area.Crosses(overpass.Point{Lat: from.Lat, Lon: from.Lon}, overpass.Point{Lat: to.Lat, Lon: to.Lon}) {
+			return true
+		}
+	}
+
+	return false
+}
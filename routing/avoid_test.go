@@ -0,0 +1,66 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/yurachistic1/routeplanner-backend/overpass"
+)
+
+func TestAvoidApply(t *testing.T) {
+
+	//  1 - 2 - 3
+	//  |   |   |
+	//  4 - 5 - 6
+	g := testGraph(
+		map[Id][2]float64{1: {1, 0}, 2: {1, 1}, 3: {1, 2}, 4: {0, 0}, 5: {0, 1}, 6: {0, 2}},
+		[][2]Id{{1, 2}, {2, 3}, {1, 4}, {4, 5}, {5, 6}, {3, 6}, {2, 5}},
+		nil,
+	)
+
+	// the middle edge is way 7
+	e25, e52 := g[2].Edges[5], g[5].Edges[2]
+	e25.WayId, e52.WayId = 7, 7
+	g[2].Edges[5], g[5].Edges[2] = e25, e52
+
+	// a box around the middle of the edge between 5 and 6, without any nodes
+	lat, lon := (g[5].Lat+g[6].Lat)/2, (g[5].Lon+g[6].Lon)/2
+	box := overpass.Polygon{
+		{Lat: lat - 0.0001, Lon: lon - 0.0001},
+		{Lat: lat - 0.0001, Lon: lon + 0.0001},
+		{Lat: lat + 0.0001, Lon: lon + 0.0001},
+		{Lat: lat + 0.0001, Lon: lon - 0.0001},
+	}
+
+	cases := []struct {
+		avoid Avoid
+		want  []Id
+	}{
+		{Avoid{}, []Id{1, 2, 3, 4, 5, 6}},
+		{Avoid{Ways: map[int]bool{7: true}}, []Id{1, 2, 3, 4, 5, 6}},
+		{Avoid{Areas: []overpass.Polygon{box}}, []Id{1, 2, 4, 5}},
+		{Avoid{Ways: map[int]bool{7: true}, Areas: []overpass.Polygon{box}}, []Id{}},
+	}
+
+	for _, c := range cases {
+		result := c.avoid.Apply(g)
+
+		if len(result) != len(c.want) {
+			t.Errorf("Apply(%+v) == %v, want nodes %v", c.avoid, result, c.want)
+			continue
+		}
+
+		for _, id := range c.want {
+			if result[id] == nil {
+				t.Errorf("Apply(%+v) removed node %v", c.avoid, id)
+			}
+		}
+	}
+
+	if len(Avoid{Ways: map[int]bool{7: true}}.Apply(g)[2].Adjacent) != 2 {
+		t.Errorf("Apply() kept the avoided way")
+	}
+
+	if len(g) != 6 || len(g[2].Adjacent) != 3 {
+		t.Errorf("Apply() modified the original graph")
+	}
+}
@@ -0,0 +1,145 @@
+package routing
+
+import (
+	"math"
+)
+
+// Action enum type
+type Action int
+
+// Action of a maneuver, turns are named by how sharp they are.
+const (
+	Depart Action = iota
+	Continue
+	SlightLeft
+	SlightRight
+	Left
+	Right
+	SharpLeft
+	SharpRight
+	UTurn
+	// TakeSteps is going on along steps without turning.
+	TakeSteps
+	Arrive
+)
+
+// Names of actions as they are given to clients.
+var actionNames = [...]string{
+	Depart:      "depart",
+	Continue:    "continue",
+	SlightLeft:  "slight left",
+	SlightRight: "slight right",
+	Left:        "left",
+	Right:       "right",
+	SharpLeft:   "sharp left",
+	SharpRight:  "sharp right",
+	UTurn:       "uturn",
+	TakeSteps:   "steps",
+	Arrive:      "arrive",
+}
+
+func (a Action) String() string {
+	return actionNames[a]
+}
+
+// Maneuver is a step of the directions of a route, what to do at a node and
+// how far to go before the next maneuver.
+type Maneuver struct {
+	Action Action
+
+	// Index in Path of the node at which the maneuver is made.
+	Index int
+
+	// Distance in meters to the next maneuver.
+	Distance float64
+
+	// Way followed after the maneuver, nil when arriving or when the edge has
+	// no attributes.
+	Way *Way
+
+	// Bearing in degrees of the edge taken after the maneuver.
+	Bearing float64
+}
+
+// Turns sharper than this many degrees are sharp and ones up to slightTurn
+// are slight, the rest are ordinary turns.
+const (
+	slightTurn = 60
+	sharpTurn  = 135
+	uTurn      = 170
+)
+
+// Maneuvers returns directions for following the route. A maneuver is made at
+// every turn, the same ones returned by TurnPoints, and wherever the route
+// goes on onto a way with a different name or onto steps.
+func (route Route) Maneuvers() []Maneuver {
+
+	if len(route.Path) < 2 {
+		return []Maneuver{}
+	}
+
+	first := route.Path[0].Edges[route.Path[1].Id]
+	maneuvers := []Maneuver{{Action: Depart, Way: first.Way, Bearing: first.Bearing}}
+
+	for i := 1; i < len(route.Path); i++ {
+		before := route.Path[i-1].Edges[route.Path[i].Id]
+		maneuvers[len(maneuvers)-1].Distance += before.Distance
+
+		if i == len(route.Path)-1 {
+			break
+		}
+
+		after := route.Path[i].Edges[route.Path[i+1].Id]
+		action := Continue
+
+		if bearingDifference(before.Bearing, after.Bearing) > turnAngle {
+			action = turnAction(turnDirection(before.Bearing, after.Bearing))
+		} else if highway(after.Way) == "steps" && highway(before.Way) != "steps" {
+			action = TakeSteps
+		} else if name(after.Way) == name(before.Way) {
+			continue
+		}
+
+		maneuvers = append(maneuvers, Maneuver{Action: action, Index: i, Way: after.Way, Bearing: after.Bearing})
+	}
+
+	return append(maneuvers, Maneuver{Action: Arrive, Index: len(route.Path) - 1})
+}
+
+// TurnAction returns the action of turning by the angle, positive to the
+// right.
+func turnAction(angle float64) Action {
+
+	sharpness := math.Abs(angle)
+
+	switch {
+	case sharpness > uTurn:
+		return UTurn
+	case sharpness > sharpTurn && angle < 0:
+		return SharpLeft
+	case sharpness > sharpTurn:
+		return SharpRight
+	case sharpness > slightTurn && angle < 0:
+		return Left
+	case sharpness > slightTurn:
+		return Right
+	case angle < 0:
+		return SlightLeft
+	}
+
+	return SlightRight
+}
+
+func name(way *Way) string {
+	if way == nil {
+		return ""
+	}
+	return way.Name
+}
+
+func highway(way *Way) string {
+	if way == nil {
+		return ""
+	}
+	return way.Highway
+}
@@ -0,0 +1,94 @@
+package routing
+
+import (
+	"math"
+	"testing"
+)
+
+func TestManeuvers(t *testing.T) {
+
+	//          0
+	//          |
+	//  1 - 2 - 3
+	//  |       |
+	//  4 - 5 - 6 - 7
+	high := &Way{Highway: "residential", Name: "High Street"}
+	mill := &Way{Highway: "residential", Name: "Mill Lane"}
+	steps := &Way{Highway: "steps"}
+
+	g := testGraph(
+		map[Id][2]float64{0: {2, 2}, 1: {1, 0}, 2: {1, 1}, 3: {1, 2}, 4: {0, 0}, 5: {0, 1}, 6: {0, 2}, 7: {0, 3}},
+		[][2]Id{{0, 3}, {1, 2}, {2, 3}, {1, 4}, {4, 5}, {5, 6}, {3, 6}, {6, 7}},
+		map[[2]Id]*Way{
+			{0, 3}: {Highway: "footway", Name: "Church Path"},
+			{1, 2}: high, {2, 3}: high,
+			{4, 5}: mill, {5, 6}: mill,
+			{3, 6}: steps,
+			{6, 7}: {Highway: "residential", Name: "Station Road"},
+		},
+	)
+
+	type step struct {
+		action Action
+		index  int
+		way    *Way
+	}
+
+	cases := []struct {
+		path []Id
+		want []step
+	}{
+		{[]Id{4, 5, 6}, []step{{Depart, 0, mill}, {Arrive, 2, nil}}},
+		{[]Id{1, 2, 3, 6, 5, 4}, []step{{Depart, 0, high}, {Right, 2, steps}, {Right, 3, mill}, {Arrive, 5, nil}}},
+		{[]Id{0, 3, 6}, []step{{Depart, 0, g[0].Edges[3].Way}, {TakeSteps, 1, steps}, {Arrive, 2, nil}}},
+		{[]Id{4, 5, 6, 7}, []step{{Depart, 0, mill}, {Continue, 2, g[6].Edges[7].Way}, {Arrive, 3, nil}}},
+		{[]Id{4, 5, 4}, []step{{Depart, 0, mill}, {UTurn, 1, mill}, {Arrive, 2, nil}}},
+		{[]Id{4}, []step{}},
+	}
+
+	for _, c := range cases {
+		route := routeFromPath(c.path, g, nil)
+		got := route.Maneuvers()
+
+		if len(got) != len(c.want) {
+			t.Errorf("%v.Maneuvers() == %+v, want %+v", c.path, got, c.want)
+			continue
+		}
+
+		total := 0.0
+
+		for i, m := range got {
+			if (step{m.Action, m.Index, m.Way}) != c.want[i] {
+				t.Errorf("%v.Maneuvers()[%d] == %v at %d, want %v at %d", c.path, i, m.Action, m.Index, c.want[i].action, c.want[i].index)
+			}
+			total += m.Distance
+		}
+
+		if math.Abs(total-route.Length) > 1e-9 {
+			t.Errorf("%v: maneuvers cover %v, route is %v long", c.path, total, route.Length)
+		}
+	}
+}
+
+func TestTurnAction(t *testing.T) {
+
+	cases := []struct {
+		angle float64
+		want  Action
+	}{
+		{50, SlightRight},
+		{-50, SlightLeft},
+		{90, Right},
+		{-90, Left},
+		{150, SharpRight},
+		{-150, SharpLeft},
+		{175, UTurn},
+		{-180, UTurn},
+	}
+
+	for _, c := range cases {
+		if got := turnAction(c.angle); got != c.want {
+			t.Errorf("turnAction(%v) == %v, want %v", c.angle, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package routing
+
+import (
+	"math"
+)
+
+// Minimum distance in meters over which grades are measured, over shorter
+// stretches errors of elevation models are exaggerated.
+const gradeWindow = 50
+
+// Distances returns the distance in meters from the start of the route to
+// each node of its path.
+func (route Route) Distances() []float64 {
+
+	distances := make([]float64, len(route.Path))
+
+	for i := 1; i < len(route.Path); i++ {
+		distances[i] = distances[i-1] + route.Path[i-1].Edges[route.Path[i].Id].Distance
+	}
+
+	return distances
+}
+
+// MaxGrade returns the steepest grade of the route in percent, uphill or
+// downhill, measured over stretches of at least gradeWindow meters unless the
+// route is shorter. Nodes with unknown elevation are left out.
+func (route Route) MaxGrade() float64 {
+
+	type point struct {
+		distance, elevation float64
+	}
+
+	distances := route.Distances()
+	points := []point{}
+
+	for i, node := range route.Path {
+		if !math.IsNaN(node.Elevation) {
+			points = append(points, point{distances[i], node.Elevation})
+		}
+	}
+
+	max := 0.0
+
+	for i, j := 0, 0; i < len(points); i++ {
+		for j < len(points)-1 && points[j].distance-points[i].distance < gradeWindow {
+			j++
+		}
+
+		run := points[j].distance - points[i].distance
+
+		// the rest of the route is too short, unless it is all there is
+		if run == 0 || (run < gradeWindow && i > 0) {
+			break
+		}
+
+		grade := math.Abs(points[j].elevation-points[i].elevation) / run * 100
+		max = math.Max(max, grade)
+	}
+
+	return max
+}
@@ -0,0 +1,60 @@
+package routing
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistances(t *testing.T) {
+
+	g := gridGraph(3)
+	route := routeFromPath([]Id{0, 1, 2, 5}, g, nil)
+
+	distances := route.Distances()
+	want := []float64{0, Haversine(g[0], g[1])}
+	want = append(want, want[1]+Haversine(g[1], g[2]))
+	want = append(want, want[2]+Haversine(g[2], g[5]))
+
+	for i := range want {
+		if math.Abs(distances[i]-want[i]) > 1e-9 {
+			t.Errorf("Distances() == %v, want %v", distances, want)
+			break
+		}
+	}
+
+	if math.Abs(distances[3]-route.Length) > 1e-9 {
+		t.Errorf("Distances() ends at %v, route is %v long", distances[3], route.Length)
+	}
+}
+
+func TestMaxGrade(t *testing.T) {
+
+	// nodes about 100m apart
+	g := gridGraph(6)
+
+	cases := []struct {
+		elevations []float64
+		want       float64
+	}{
+		{[]float64{10, 10, 10, 10}, 0},
+		{[]float64{10, 15, 20, 20}, 5},
+		{[]float64{20, 20, 12, 20}, 8},
+		{[]float64{10, math.NaN(), 16, 16}, 3},
+		{[]float64{math.NaN(), math.NaN(), math.NaN(), math.NaN()}, 0},
+	}
+
+	for _, c := range cases {
+		ids := []Id{0, 1, 2, 3}
+		for i, id := range ids {
+			g[id].Elevation = c.elevations[i]
+		}
+
+		route := routeFromPath(ids, g, nil)
+		result := route.MaxGrade()
+
+		// the grid is not exactly 100m
+		if math.Abs(result-c.want) > c.want*0.01 {
+			t.Errorf("MaxGrade() of %v == %v, want %v", c.elevations, result, c.want)
+		}
+	}
+}
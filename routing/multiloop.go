@@ -0,0 +1,77 @@
+package routing
+
+import (
+	"sort"
+)
+
+// Maximum similarity in percent between the loops of a MultiLoop route.
+const maxLoopSimilarity = 30
+
+// MultiLoops returns routes made of several loops through the same start
+// node, each a share of the distance. The loops are the best single loops
+// found by TopRoutes combined so that they cover different ground.
+func multiLoops(lat, lon, distance float64, graph Graph, opts Options) Routes {
+
+	n := opts.loops()
+
+	single := opts
+	single.Shape = Loop
+
+	loops := TopRoutes(lat, lon, distance/float64(n), graph, single)
+
+	top := make(Routes, 0, 25)
+	scorer := opts.scorer(DefaultScorer)
+
+	for i := range loops {
+		combo := Routes{loops[i]}
+
+		for j := i + 1; j < len(loops) && len(combo) < n; j++ {
+			if loops[j].Path[0] == loops[i].Path[0] && separate(loops[j], combo) {
+				combo = append(combo, loops[j])
+			}
+		}
+
+		if len(combo) < n {
+			continue
+		}
+
+		route := joinLoops(combo, graph, opts.Profile)
+		route.DesiredLength = distance
+		scorer.Score(&route)
+		top = appendRoute(route, top)
+	}
+
+	sort.Sort(top)
+
+	return top
+}
+
+// Separate reports whether the loop shares little with any of the loops.
+func separate(loop Route, loops Routes) bool {
+	for _, l := range loops {
+		if routeSimilarity(loop, l) > maxLoopSimilarity {
+			return false
+		}
+	}
+	return true
+}
+
+// JoinLoops returns a single route going around all the loops in order. The
+// loops have to start and end at the same node.
+func joinLoops(loops Routes, graph Graph, profile *Profile) Route {
+
+	ids := []Id{loops[0].Path[0].Id}
+	boundaries := make([]int, 0, len(loops))
+
+	for _, loop := range loops {
+		ids = append(ids, pathIds(loop.Path[1:])...)
+		boundaries = append(boundaries, len(ids)-1)
+	}
+
+	route := routeFromPath(ids, graph, profile)
+	route.Boundaries = boundaries
+	// the start is passed at the end of every loop
+	route.IntendedRepeats = len(loops)
+
+	return route
+}
@@ -0,0 +1,66 @@
+package routing
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJoinLoops(t *testing.T) {
+
+	//  1 - 2 - 3
+	//  |   |   |
+	//  4 - 5 - 6
+	g := testGraph(
+		map[Id][2]float64{1: {1, 0}, 2: {1, 1}, 3: {1, 2}, 4: {0, 0}, 5: {0, 1}, 6: {0, 2}},
+		[][2]Id{{1, 2}, {2, 3}, {1, 4}, {4, 5}, {5, 6}, {3, 6}, {2, 5}},
+		nil,
+	)
+
+	left := routeFromPath([]Id{2, 1, 4, 5, 2}, g, nil)
+	right := routeFromPath([]Id{2, 3, 6, 5, 2}, g, nil)
+
+	route := joinLoops(Routes{left, right}, g, nil)
+
+	if want := []Id{2, 1, 4, 5, 2, 3, 6, 5, 2}; !reflect.DeepEqual(pathIds(route.Path), want) {
+		t.Errorf("joinLoops() path == %v, want %v", pathIds(route.Path), want)
+	}
+
+	if want := []int{4, 8}; !reflect.DeepEqual(route.Boundaries, want) {
+		t.Errorf("joinLoops() boundaries == %v, want %v", route.Boundaries, want)
+	}
+
+	if route.Length != left.Length+right.Length {
+		t.Errorf("joinLoops() length == %v, want %v", route.Length, left.Length+right.Length)
+	}
+
+	// node 5 is shared by the loops, the start is intended
+	if route.RepeatVisits-route.IntendedRepeats != 1 {
+		t.Errorf("joinLoops() unintended repeats == %d, want 1", route.RepeatVisits-route.IntendedRepeats)
+	}
+}
+
+func TestMultiLoops(t *testing.T) {
+
+	g := gridGraph(12)
+	start := g[66]
+
+	for _, n := range []int{2, 3} {
+		routes := TopRoutes(start.Lat, start.Lon, 2400, g, Options{Shape: MultiLoop, Loops: n})
+
+		if len(routes) == 0 {
+			t.Fatalf("%d loops: no routes returned", n)
+		}
+
+		for _, r := range routes {
+			if len(r.Boundaries) != n || r.Boundaries[n-1] != len(r.Path)-1 {
+				t.Fatalf("%d loops: boundaries == %v for %d nodes", n, r.Boundaries, len(r.Path))
+			}
+
+			for _, b := range r.Boundaries {
+				if r.Path[b] != r.Path[0] {
+					t.Errorf("%d loops: loop ending at %d is not back at the start", n, b)
+				}
+			}
+		}
+	}
+}
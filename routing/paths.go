@@ -0,0 +1,135 @@
+package routing
+
+import (
+	"sort"
+)
+
+// Factor by which the cost of edges used by previously found paths is
+// multiplied when searching for alternatives.
+const alternativePenalty = 1.6
+
+// PathScorer ranks point to point routes, which are judged mainly by length.
+var PathScorer = Scorer{
+	{"distance", 1, func(r *Route) float64 {
+		return r.Length
+	}},
+	{"turns", 10, func(r *Route) float64 {
+		return float64(r.Turns)
+	}},
+	{"profile", 1, func(r *Route) float64 {
+		return r.Penalty
+	}},
+	{"climb", 0, climb},
+}
+
+// ShortestRoutes returns the cheapest route between two locations followed by
+// up to n alternatives that are sufficiently distinct from each other and
+// not much longer. Alternatives are found by making the edges of the routes
+// already found more expensive and searching again, they are ordered by score
+// while the cheapest route always comes first.
+func ShortestRoutes(fromLat, fromLon, toLat, toLon float64, graph Graph, n int, opts Options) (Routes, error) {
+
+	from := ClosestNodes(fromLat, fromLon, graph, 1)
+	to := ClosestNodes(toLat, toLon, graph, 1)
+
+	if len(from) == 0 || len(to) == 0 {
+		return nil, ErrUnreachable
+	}
+
+	scorer := opts.scorer(PathScorer)
+
+	cost := opts.cost()
+	used := make(map[[2]Id]bool)
+
+	penalised := func(a, b Id, e Edge) float64 {
+		if used[[2]Id{a, b}] {
+			return cost(e) * alternativePenalty
+		}
+		return cost(e)
+	}
+
+	routes := Routes{}
+
+	for attempt := 0; attempt <= 3*n && len(routes) <= n; attempt++ {
+		ids, err := aStar(from[0], to[0], graph, penalised, opts.budget())
+
+		if err != nil {
+			if len(routes) == 0 {
+				return nil, err
+			}
+			break
+		}
+
+		route := routeFromPath(ids, graph, opts.Profile)
+		scorer.Score(&route)
+
+		for i := 0; i < len(ids)-1; i++ {
+			used[[2]Id{ids[i], ids[i+1]}] = true
+			used[[2]Id{ids[i+1], ids[i]}] = true
+		}
+
+		if len(routes) > 0 && route.Length > routes[0].Length*1.5 {
+			break
+		}
+
+		if distinct(route, routes) {
+			routes = append(routes, route)
+		}
+	}
+
+	sort.Stable(routes[1:])
+
+	return routes, nil
+}
+
+// Distinct reports whether the route is not too similar to any of the routes.
+func distinct(route Route, routes Routes) bool {
+	for _, r := range routes {
+		if routeSimilarity(route, r) > 70 {
+			return false
+		}
+	}
+	return true
+}
+
+// RouteFromPath returns a Route following the node ids with its length,
+// turns and other properties set.
+func routeFromPath(ids []Id, graph Graph, profile *Profile) Route {
+
+	route := Route{
+		Path:    make([]*Node, 0, len(ids)),
+		Visited: make(map[Id]int),
+	}
+
+	for i, id := range ids {
+		node := graph[id]
+
+		route.Path = append(route.Path, node)
+		route.Visited[id] += 1
+
+		if route.Visited[id] > 1 {
+			route.RepeatVisits += 1
+		}
+
+		if i == 0 {
+			continue
+		}
+
+		edge := graph[ids[i-1]].Edges[id]
+		route.Length += edge.Distance
+		route.Penalty += profile.Penalty(edge)
+		route.Ascent += edge.Gain
+		route.Descent += edge.Loss
+
+		if i > 1 {
+			previous := graph[ids[i-2]].Edges[ids[i-1]]
+			if bearingDifference(previous.Bearing, edge.Bearing) > turnAngle {
+				route.Turns++
+			}
+		}
+	}
+
+	route.DesiredLength = route.Length
+
+	return route
+}
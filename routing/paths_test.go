@@ -0,0 +1,81 @@
+package routing
+
+import (
+	"testing"
+)
+
+func TestShortestRoutes(t *testing.T) {
+
+	//  1 - 2 - 3
+	//  |   |   |
+	//  4 - 5 - 6     7 - 8
+	g := testGraph(
+		map[Id][2]float64{1: {1, 0}, 2: {1, 1}, 3: {1, 2}, 4: {0, 0}, 5: {0, 1}, 6: {0, 2}, 7: {0, 4}, 8: {0, 5}},
+		[][2]Id{{1, 2}, {2, 3}, {1, 4}, {4, 5}, {5, 6}, {3, 6}, {2, 5}, {7, 8}},
+		nil,
+	)
+
+	routes, err := ShortestRoutes(g[4].Lat, g[4].Lon, g[3].Lat, g[3].Lon, g, 2, Options{})
+
+	if err != nil {
+		t.Fatalf("ShortestRoutes(4, 3) returned %v", err)
+	}
+
+	if len(routes) < 2 {
+		t.Fatalf("ShortestRoutes(4, 3) returned %d routes, want at least 2", len(routes))
+	}
+
+	for i, r := range routes {
+		if r.Path[0].Id != 4 || r.Path[len(r.Path)-1].Id != 3 {
+			t.Errorf("route %d leads from %v to %v, want 4 to 3", i, r.Path[0].Id, r.Path[len(r.Path)-1].Id)
+		}
+
+		if r.Length > routes[0].Length*1.5 {
+			t.Errorf("route %d is %.0fm long, shortest is %.0fm", i, r.Length, routes[0].Length)
+		}
+
+		if i > 1 && r.Score < routes[i-1].Score {
+			t.Errorf("alternatives are not ordered by score")
+		}
+	}
+
+	// a scorer favouring long routes does not displace the shortest one
+	longest := Scorer{{"distance", -1, func(r *Route) float64 { return r.Length }}}
+	favoured, _ := ShortestRoutes(g[4].Lat, g[4].Lon, g[3].Lat, g[3].Lon, g, 2, Options{Scorer: longest})
+
+	if len(favoured) < 2 || favoured[0].Length != routes[0].Length || favoured[0].Length > favoured[1].Length {
+		t.Errorf("ShortestRoutes(4, 3) with a scorer favouring long routes does not start with the shortest one")
+	}
+
+	if _, err := ShortestRoutes(g[1].Lat, g[1].Lon, g[8].Lat, g[8].Lon, g, 2, Options{}); err != ErrUnreachable {
+		t.Errorf("ShortestRoutes(1, 8) error == %v, want %v", err, ErrUnreachable)
+	}
+}
+
+func TestRouteFromPath(t *testing.T) {
+
+	//  1 - 2
+	//      |
+	//  4 - 5
+	g := testGraph(
+		map[Id][2]float64{1: {1, 0}, 2: {1, 1}, 4: {0, 0}, 5: {0, 1}},
+		[][2]Id{{1, 2}, {2, 5}, {4, 5}},
+		map[[2]Id]*Way{{2, 5}: {Highway: "steps"}},
+	)
+
+	route := routeFromPath([]Id{1, 2, 5, 4}, g, NoSteps)
+
+	length := Haversine(g[1], g[2]) + Haversine(g[2], g[5]) + Haversine(g[5], g[4])
+
+	if route.Length != length || route.DesiredLength != length {
+		t.Errorf("route length == %v, want %v", route.Length, length)
+	}
+
+	if route.Turns != 2 {
+		t.Errorf("route turns == %d, want 2", route.Turns)
+	}
+
+	if want := 19 * Haversine(g[2], g[5]); route.Penalty != want {
+		t.Errorf("route penalty == %v, want %v", route.Penalty, want)
+	}
+}
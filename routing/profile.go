@@ -0,0 +1,154 @@
+package routing
+
+// Profile weights edges by the attributes of their way. Factors multiply the
+// length of an edge, 1 is neutral and larger values make an edge less
+// desirable. Values that are missing from the maps count as 1.
+type Profile struct {
+	Name       string
+	Surface    map[string]float64
+	Highway    map[string]float64
+	Smoothness map[string]float64
+
+	// Weights scale the weights of the named components of the scorer the
+	// routes are ranked with, e.g. 0.5 halves the weight of turns.
+	Weights map[string]float64
+}
+
+// Angle in degrees that an edge with a factor of 2 is considered to be off
+// the target bearing when picking the next edge of a route.
+const profileAngle = 30
+
+// Predefined profiles selectable by name.
+var (
+	Default = &Profile{Name: "default"}
+
+	Paved = &Profile{
+		Name: "paved",
+		Surface: map[string]float64{
+			"unpaved":     6,
+			"compacted":   2,
+			"fine_gravel": 3,
+			"gravel":      5,
+			"pebblestone": 5,
+			"dirt":        8,
+			"earth":       8,
+			"ground":      8,
+			"grass":       10,
+			"mud":         10,
+			"sand":        10,
+			"woodchips":   8,
+		},
+		Highway: map[string]float64{
+			"track":     3,
+			"path":      3,
+			"bridleway": 4,
+			"steps":     2,
+		},
+		Smoothness: map[string]float64{
+			"bad":           2,
+			"very_bad":      3,
+			"horrible":      5,
+			"very_horrible": 8,
+			"impassable":    10,
+		},
+	}
+
+	Trails = &Profile{
+		Name: "trails",
+		Surface: map[string]float64{
+			"asphalt":         2,
+			"concrete":        2,
+			"paving_stones":   2,
+			"sett":            1.5,
+			"concrete:plates": 2,
+		},
+		Highway: map[string]float64{
+			"secondary":     4,
+			"tertiary":      3,
+			"unclassified":  2,
+			"residential":   2.5,
+			"living_street": 2,
+			"pedestrian":    2,
+			"footway":       1.3,
+			"crossing":      1.5,
+		},
+		// trails wind a lot more than roads do
+		Weights: map[string]float64{
+			"turns": 0.5,
+		},
+	}
+
+	NoSteps = &Profile{
+		Name: "nosteps",
+		Highway: map[string]float64{
+			"steps": 20,
+		},
+	}
+
+	Profiles = map[string]*Profile{
+		Default.Name: Default,
+		Paved.Name:   Paved,
+		Trails.Name:  Trails,
+		NoSteps.Name: NoSteps,
+	}
+)
+
+// Factor returns the multiplier of the length of an edge. Edges without way
+// attributes and a nil profile are neutral.
+func (p *Profile) Factor(e Edge) float64 {
+
+	if p == nil || e.Way == nil {
+		return 1
+	}
+
+	factor := 1.0
+
+	for _, weight := range []struct {
+		values map[string]float64
+		value  string
+	}{
+		{p.Surface, e.Way.Surface},
+		{p.Highway, e.Way.Highway},
+		{p.Smoothness, e.Way.Smoothness},
+	} {
+		if f, ok := weight.values[weight.value]; ok {
+			factor *= f
+		}
+	}
+
+	return factor
+}
+
+// Penalty returns the extra length in meters an edge is considered to have.
+func (p *Profile) Penalty(e Edge) float64 {
+	return e.Distance * (p.Factor(e) - 1)
+}
+
+// Cost is a CostFunc weighting the length of edges by the profile.
+func (p *Profile) Cost(e Edge) float64 {
+	return e.Distance * p.Factor(e)
+}
+
+// Scorer returns a copy of base with the weights of its components scaled by
+// the profile. Unknown names are reported as an error, a nil profile returns
+// base unchanged.
+func (p *Profile) Scorer(base Scorer) (Scorer, error) {
+
+	if p == nil {
+		return base, nil
+	}
+
+	weights := make(map[string]float64, len(p.Weights))
+
+	for name, factor := range p.Weights {
+		weights[name] = factor
+
+		for _, c := range base {
+			if c.Name == name {
+				weights[name] = c.Weight * factor
+			}
+		}
+	}
+
+	return base.WithWeights(weights)
+}
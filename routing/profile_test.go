@@ -0,0 +1,81 @@
+package routing
+
+import (
+	"testing"
+)
+
+func TestProfileFactor(t *testing.T) {
+
+	var (
+		gravelPath = &Way{Highway: "path", Surface: "gravel"}
+		pavement   = &Way{Highway: "footway", Surface: "asphalt"}
+		steps      = &Way{Highway: "steps"}
+		badTrack   = &Way{Highway: "track", Smoothness: "horrible"}
+	)
+
+	cases := []struct {
+		profile *Profile
+		edge    Edge
+		want    float64
+	}{
+		{nil, Edge{Way: gravelPath}, 1},
+		{Default, Edge{Way: gravelPath}, 1},
+		{Paved, Edge{}, 1},
+		{Paved, Edge{Way: gravelPath}, 15},
+		{Paved, Edge{Way: pavement}, 1},
+		{Paved, Edge{Way: badTrack}, 15},
+		{Trails, Edge{Way: gravelPath}, 1},
+		{Trails, Edge{Way: pavement}, 2.6},
+		{NoSteps, Edge{Way: steps}, 20},
+		{NoSteps, Edge{Way: pavement}, 1},
+	}
+
+	for _, c := range cases {
+		if result := c.profile.Factor(c.edge); result != c.want {
+			t.Errorf("%v.Factor(%+v) == %v, want %v", c.profile, c.edge.Way, result, c.want)
+		}
+	}
+
+	if p := Paved.Penalty(Edge{Distance: 100, Way: steps}); p != 100 {
+		t.Errorf("Paved.Penalty() == %v, want 100", p)
+	}
+}
+
+func TestPickAlongBearingProfile(t *testing.T) {
+
+	edges := map[Id]Edge{
+		2: {Bearing: 10, Way: &Way{Highway: "steps"}},
+		3: {Bearing: 50, Way: &Way{Highway: "footway"}},
+	}
+
+	cases := []struct {
+		profile *Profile
+		want    Id
+	}{
+		{nil, 2},
+		{Paved, 2},
+		{NoSteps, 3},
+	}
+
+	for _, c := range cases {
+		if result := pickAlongBearing(0, edges, -1, c.profile); result != c.want {
+			t.Errorf("pickAlongBearing(0, %v, -1, %v) == %v, want %v",
+				edges, c.profile, result, c.want)
+		}
+	}
+}
+
+func TestProfileScorer(t *testing.T) {
+
+	for name, profile := range Profiles {
+		for _, base := range []Scorer{DefaultScorer, PathScorer} {
+			if _, err := profile.Scorer(base); err != nil {
+				t.Errorf("Profiles[%q].Scorer() returned error %v", name, err)
+			}
+		}
+	}
+
+	if _, err := (&Profile{Weights: map[string]float64{"hills": 2}}).Scorer(DefaultScorer); err == nil {
+		t.Errorf("Scorer() returned no error for unknown component")
+	}
+}
@@ -12,29 +12,58 @@ import (
 // supplied criteria such as distance as well as implicit criteria such as No of
 // turns and others. Lots of possible ones are generated and then best 25 are
 // selected and returned.
-func TopRoutes(lat, lon, distance float64, graph Graph) Routes {
+func TopRoutes(lat, lon, distance float64, graph Graph, opts Options) Routes {
 
-	nodes := ClosestNodes(lat, lon, graph, 3)
+	if len(opts.Waypoints) > 0 {
+		return viaRoutes(lat, lon, distance, graph, opts)
+	}
+
+	if opts.Shape == MultiLoop {
+		return multiLoops(lat, lon, distance, graph, opts)
+	}
+
+	return topRoutesFrom(ClosestNodes(lat, lon, graph, 3), distance, graph, opts)
+}
+
+// TopRoutesFrom is TopRoutes for routes starting at any of the nodes.
+func topRoutesFrom(nodes []*Node, distance float64, graph Graph, opts Options) Routes {
 
 	top := make(Routes, 0, 25)
+	scorer := opts.scorer(DefaultScorer)
 
 	for _, start := range nodes {
 
 		for i := 0; i < 360; i += 20 {
 
 			for j := 0; j < 50; j++ {
-				r1 := createRoute(start, distance, float64(i), graph, Clockwise)
-				top = appendRoute(r1, top)
-				r2 := createRoute(start, distance, float64(i), graph, Anticlockwise)
-				top = appendRoute(r2, top)
+				for _, r := range createShaped(start, distance, float64(i), graph, opts) {
+					scorer.Score(&r)
+					top = appendRoute(r, top)
+				}
 			}
 		}
 	}
 
-	for i, r := range top {
-		top[i] = completeRoute(r, graph)
+	// out and back routes are complete as created
+	if opts.Shape == OutAndBack {
+		sort.Sort(top)
+		return top
+	}
+
+	// routes that cannot be closed into a loop are dropped
+	complete := top[:0]
+
+	for _, r := range top {
+		r, err := completeRoute(r, graph, opts)
+		if err != nil {
+			continue
+		}
+		scorer.Score(&r)
+		complete = append(complete, r)
 	}
 
+	top = complete
+
 	sort.Sort(top)
 
 	return top
@@ -92,7 +121,8 @@ func appendRoute(route Route, routes Routes) Routes {
 }
 
 // Create route returns a circular Route of desired distance at a specified start location.
-func createRoute(start *Node, distance, initBearing float64, g Graph, rot Rotation) Route {
+// Edges that the profile considers less desirable are less likely to be picked.
+func createRoute(start *Node, distance, initBearing float64, g Graph, rot Rotation, profile *Profile) Route {
 
 	route := Route{
 		Path:          make([]*Node, 1, 1000),
@@ -129,8 +159,8 @@ func createRoute(start *Node, distance, initBearing float64, g Graph, rot Rotati
 
 		}
 
-		steer := pickAlongBearing(b, currentNode.Edges, previousNode.Id)
-		straight := pickAlongBearing(currentBearing, currentNode.Edges, previousNode.Id)
+		steer := pickAlongBearing(b, currentNode.Edges, previousNode.Id, profile)
+		straight := pickAlongBearing(currentBearing, currentNode.Edges, previousNode.Id, profile)
 
 		pick := 0
 		choices := []Id{steer, straight}
@@ -140,12 +170,17 @@ func createRoute(start *Node, distance, initBearing float64, g Graph, rot Rotati
 
 		}
 
+		edge := currentNode.Edges[choices[pick]]
+
 		route.Path = append(route.Path, (g)[choices[pick]])
-		route.Length += currentNode.Edges[choices[pick]].Distance
+		route.Length += edge.Distance
+		route.Penalty += profile.Penalty(edge)
+		route.Ascent += edge.Gain
+		route.Descent += edge.Loss
 
 		newBearing = (g)[currentNode.Id].Edges[choices[pick]].Bearing
 
-		if len(route.Path) > 1 && bearingDifference(currentBearing, newBearing) > 45 {
+		if len(route.Path) > 1 && bearingDifference(currentBearing, newBearing) > turnAngle {
 			route.Turns++
 		}
 
@@ -160,9 +195,72 @@ func createRoute(start *Node, distance, initBearing float64, g Graph, rot Rotati
 	return route
 }
 
+// CreateShaped returns candidate routes of the shape in opts starting off
+// along the initial bearing. Loops and lollipops still have to be completed.
+func createShaped(start *Node, distance, initBearing float64, g Graph, opts Options) Routes {
+
+	switch opts.Shape {
+	case OutAndBack:
+		return Routes{createOutAndBack(start, distance, initBearing, g, opts.Profile)}
+	case Lollipop:
+		return Routes{
+			createLollipop(start, distance, initBearing, g, Clockwise, opts.Profile),
+			createLollipop(start, distance, initBearing, g, Anticlockwise, opts.Profile),
+		}
+	}
+
+	return Routes{
+		createRoute(start, distance, initBearing, g, Clockwise, opts.Profile),
+		createRoute(start, distance, initBearing, g, Anticlockwise, opts.Profile),
+	}
+}
+
+// CreateOutAndBack returns a Route that heads out along the initial bearing
+// for half the distance and comes back the same way.
+func createOutAndBack(start *Node, distance, initBearing float64, g Graph, profile *Profile) Route {
+
+	out := pathIds(createRoute(start, distance/2, initBearing, g, Straight, profile).Path)
+
+	ids := append([]Id{}, out...)
+	for i := len(out) - 2; i >= 0; i-- {
+		ids = append(ids, out[i])
+	}
+
+	route := routeFromPath(ids, g, profile)
+	route.DesiredLength = distance
+	route.IntendedRepeats = len(out) - 1
+
+	return route
+}
+
+// CreateLollipop returns an incomplete Route that heads out along the initial
+// bearing on a stem and then starts a loop from its end. Once the loop is
+// completed the route comes back along the stem.
+func createLollipop(start *Node, distance, initBearing float64, g Graph, rot Rotation, profile *Profile) Route {
+
+	stem := pathIds(createRoute(start, distance*lollipopStem, initBearing, g, Straight, profile).Path)
+	loop := createRoute(g[stem[len(stem)-1]], distance*(1-2*lollipopStem), initBearing, g, rot, profile)
+
+	route := routeFromPath(append(stem[:len(stem)-1], pathIds(loop.Path)...), g, profile)
+	route.DesiredLength = distance
+	route.anchor = len(stem) - 1
+
+	return route
+}
+
+// PathIds returns the ids of the nodes.
+func pathIds(path []*Node) []Id {
+	ids := make([]Id, len(path))
+	for i, node := range path {
+		ids[i] = node.Id
+	}
+	return ids
+}
+
 // PickAlongBearing selects a an edge (connected node id) that has the closest bearing
-// to the target bearing.
-func pickAlongBearing(target float64, vals map[Id]Edge, exclude Id) (closest Id) {
+// to the target bearing. Edges weighted by the profile are treated as if they
+// were further off the target, a nil profile treats all edges equally.
+func pickAlongBearing(target float64, vals map[Id]Edge, exclude Id, profile *Profile) (closest Id) {
 
 	minDifference := math.MaxFloat64
 
@@ -172,7 +270,8 @@ func pickAlongBearing(target float64, vals map[Id]Edge, exclude Id) (closest Id)
 			continue
 		}
 
-		difference := bearingDifference(target, val.Bearing)
+		difference := bearingDifference(target, val.Bearing) +
+			(profile.Factor(val)-1)*profileAngle
 		if difference < minDifference {
 			closest = key
 			minDifference = difference
@@ -198,7 +297,7 @@ func ClosestNodes(lat float64, lon float64, g Graph, n int) (closest []*Node) {
 
 	sort.Sort(pairs)
 
-	for i := 0; i < n; i++ {
+	for i := 0; i < n && i*5 < len(pairs); i++ {
 		closest = append(closest, pairs[i*5].n)
 	}
 	return closest
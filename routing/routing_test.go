@@ -7,12 +7,12 @@ import (
 func TestPickAlongBearing(t *testing.T) {
 
 	nodeA :=
-		Node{1, 51.5307698, -0.1461484, []Id{2, 3},
-			map[Id]Edge{2: {Bearing: 165}, 3: {Bearing: 344}}}
+		Node{Id: 1, Lat: 51.5307698, Lon: -0.1461484, Adjacent: []Id{2, 3},
+			Edges: map[Id]Edge{2: {Bearing: 165}, 3: {Bearing: 344}}}
 
 	nodeB :=
-		Node{1, 51.5307698, -0.1461484, []Id{2, 3, 4, 5},
-			map[Id]Edge{
+		Node{Id: 1, Lat: 51.5307698, Lon: -0.1461484, Adjacent: []Id{2, 3, 4, 5},
+			Edges: map[Id]Edge{
 				2: {Bearing: 165},
 				3: {Bearing: 344},
 				4: {Bearing: 12},
@@ -33,7 +33,7 @@ func TestPickAlongBearing(t *testing.T) {
 
 	for _, c := range cases {
 
-		result := pickAlongBearing(c.target, c.in.Edges, c.exclude)
+		result := pickAlongBearing(c.target, c.in.Edges, c.exclude, nil)
 
 		if result != c.want {
 			t.Errorf("pickAlongBearing(%v, %v, %v) == %v, want %v",
@@ -41,3 +41,75 @@ func TestPickAlongBearing(t *testing.T) {
 		}
 	}
 }
+
+// gridGraph returns a graph of size by size nodes on a grid roughly 100m
+// apart with every node connected to its neighbours.
+func gridGraph(size int) Graph {
+
+	coords := make(map[Id][2]float64)
+	edges := [][2]Id{}
+
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			id := Id(y*size + x)
+			coords[id] = [2]float64{float64(y), float64(x)}
+			if x > 0 {
+				edges = append(edges, [2]Id{id - 1, id})
+			}
+			if y > 0 {
+				edges = append(edges, [2]Id{id - Id(size), id})
+			}
+		}
+	}
+
+	return testGraph(coords, edges, nil)
+}
+
+func TestCreateOutAndBack(t *testing.T) {
+
+	g := gridGraph(10)
+
+	route := createOutAndBack(g[0], 1000, 45, g, nil)
+
+	for i, j := 0, len(route.Path)-1; i < j; i, j = i+1, j-1 {
+		if route.Path[i] != route.Path[j] {
+			t.Fatalf("route %v does not come back the same way", pathIds(route.Path))
+		}
+	}
+
+	if want := len(route.Path) / 2; route.IntendedRepeats != want {
+		t.Errorf("IntendedRepeats == %d, want %d", route.IntendedRepeats, want)
+	}
+
+	if route.Length < 980 {
+		t.Errorf("Length == %v, want at least 980", route.Length)
+	}
+}
+
+func TestTopRoutesShapes(t *testing.T) {
+
+	g := gridGraph(10)
+	start := g[44]
+
+	for name, shape := range Shapes {
+		routes := TopRoutes(start.Lat, start.Lon, 1500, g, Options{Shape: shape})
+
+		if len(routes) == 0 {
+			t.Fatalf("%s: no routes returned", name)
+		}
+
+		for _, r := range routes {
+			if r.Path[0] != r.Path[len(r.Path)-1] {
+				t.Errorf("%s: route %v does not end at the start", name, pathIds(r.Path))
+			}
+
+			if shape != Loop && r.IntendedRepeats == 0 {
+				t.Errorf("%s: route %v has no intended repeats", name, pathIds(r.Path))
+			}
+
+			if r.Breakdown["repeats"] < 0 {
+				t.Errorf("%s: repeats score == %v", name, r.Breakdown["repeats"])
+			}
+		}
+	}
+}
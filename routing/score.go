@@ -0,0 +1,94 @@
+package routing
+
+import (
+	"fmt"
+	"math"
+)
+
+// Component is a named part of the score of a route. Measure returns the raw
+// value for a route which is multiplied by Weight.
+type Component struct {
+	Name    string
+	Weight  float64
+	Measure func(route *Route) float64
+}
+
+// Scorer computes the score of a route as the weighted sum of its components,
+// lower scores are better.
+type Scorer []Component
+
+// DefaultScorer prefers routes with few turns and repeated sections that end
+// close to the start and are close to the desired length. Repeats that are
+// intended by the shape of the route are not penalised.
+var DefaultScorer = Scorer{
+	{"turns", 30, func(r *Route) float64 {
+		return float64(r.Turns)
+	}},
+	{"distanceFromStart", 1.0 / 3, func(r *Route) float64 {
+		return Haversine(r.Path[r.anchor], r.Path[len(r.Path)-1])
+	}},
+	{"repeats", 10000, func(r *Route) float64 {
+		return float64(r.RepeatVisits-r.IntendedRepeats) / float64(len(r.Path))
+	}},
+	{"distanceError", 1.0 / 3, func(r *Route) float64 {
+		return math.Abs(r.DesiredLength - r.Length)
+	}},
+	{"profile", 1.0 / 3, func(r *Route) float64 {
+		return r.Penalty
+	}},
+	{"climb", 0, climb},
+}
+
+// Hills are the weights of the climb component for the preferences
+// selectable by name. Negative weights favour hilly routes.
+var Hills = map[string]float64{
+	"any":   0,
+	"flat":  20,
+	"hilly": -20,
+}
+
+// Climb measures the ascent of a route in meters per kilometer.
+func climb(r *Route) float64 {
+	if r.Length == 0 {
+		return 0
+	}
+	return r.Ascent / r.Length * 1000
+}
+
+// Score sets the Score of the route and the weighted value of every
+// component in Breakdown.
+func (s Scorer) Score(route *Route) {
+
+	route.Score = 0
+	route.Breakdown = make(map[string]float64, len(s))
+
+	for _, c := range s {
+		value := c.Weight * c.Measure(route)
+		route.Breakdown[c.Name] = value
+		route.Score += value
+	}
+}
+
+// WithWeights returns a copy of the scorer with weights of the named
+// components replaced. Unknown names are reported as an error.
+func (s Scorer) WithWeights(weights map[string]float64) (Scorer, error) {
+
+	scorer := append(Scorer{}, s...)
+
+	for name, weight := range weights {
+		found := false
+
+		for i := range scorer {
+			if scorer[i].Name == name {
+				scorer[i].Weight = weight
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("unknown score component %q", name)
+		}
+	}
+
+	return scorer, nil
+}
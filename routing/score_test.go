@@ -0,0 +1,83 @@
+package routing
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScorerScore(t *testing.T) {
+
+	var (
+		n1 = &Node{Id: 1, Lat: 51.5, Lon: -0.1}
+		n2 = &Node{Id: 2, Lat: 51.501, Lon: -0.1}
+	)
+
+	route := Route{
+		Path:          []*Node{n1, n2, n1, n2},
+		Length:        900,
+		DesiredLength: 1200,
+		RepeatVisits:  2,
+		Turns:         4,
+		Penalty:       60,
+	}
+
+	DefaultScorer.Score(&route)
+
+	want := map[string]float64{
+		"turns":             120,
+		"distanceFromStart": Haversine(n1, n2) / 3,
+		"repeats":           5000,
+		"distanceError":     100,
+		"profile":           20,
+	}
+
+	sum := 0.0
+
+	for name, value := range want {
+		if math.Abs(route.Breakdown[name]-value) > 1e-9 {
+			t.Errorf("Breakdown[%s] == %v, want %v", name, route.Breakdown[name], value)
+		}
+		sum += value
+	}
+
+	if math.Abs(route.Score-sum) > 1e-9 {
+		t.Errorf("Score == %v, want %v", route.Score, sum)
+	}
+}
+
+func TestScorerWithWeights(t *testing.T) {
+
+	scorer, err := DefaultScorer.WithWeights(map[string]float64{"turns": 0, "repeats": 1})
+
+	if err != nil {
+		t.Fatalf("WithWeights() returned error %v", err)
+	}
+
+	if scorer[0].Weight != 0 || scorer[2].Weight != 1 || DefaultScorer[0].Weight != 30 {
+		t.Errorf("WithWeights() == %v, DefaultScorer == %v", scorer, DefaultScorer)
+	}
+
+	if _, err := DefaultScorer.WithWeights(map[string]float64{"hills": 1}); err == nil {
+		t.Errorf("WithWeights() returned no error for unknown component")
+	}
+
+	hilly, _ := DefaultScorer.WithWeights(map[string]float64{"climb": Hills["hilly"]})
+	flat, _ := DefaultScorer.WithWeights(map[string]float64{"climb": Hills["flat"]})
+	route := Route{Path: []*Node{{}}, Length: 2000, Ascent: 100}
+
+	if hilly.Score(&route); route.Breakdown["climb"] != -1000 {
+		t.Errorf("hilly climb score == %v, want -1000", route.Breakdown["climb"])
+	}
+
+	if flat.Score(&route); route.Breakdown["climb"] != 1000 {
+		t.Errorf("flat climb score == %v, want 1000", route.Breakdown["climb"])
+	}
+
+	if s := (Options{Profile: Trails}).scorer(DefaultScorer); s[0].Weight != 15 {
+		t.Errorf("Trails scorer turns weight == %v, want 15", s[0].Weight)
+	}
+
+	if s := (Options{Profile: Trails}).scorer(PathScorer); s[1].Weight != 5 {
+		t.Errorf("Trails path scorer turns weight == %v, want 5", s[1].Weight)
+	}
+}
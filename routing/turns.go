@@ -0,0 +1,49 @@
+package routing
+
+import (
+	"math"
+)
+
+// Smallest change of bearing in degrees that counts as a turn.
+const turnAngle = 45
+
+// Turn is a change of direction on a route.
+type Turn struct {
+	// Index in Path of the node at which the route turns.
+	Index int
+
+	// Angle between the edges before and after the node in degrees, positive
+	// to the right and negative to the left.
+	Angle float64
+}
+
+// Left reports whether the route turns to the left.
+func (t Turn) Left() bool {
+	return t.Angle < 0
+}
+
+// TurnPoints returns the changes of bearing of more than turnAngle along the
+// route, the same ones that are counted in Turns of a route built from a
+// path.
+func (route Route) TurnPoints() []Turn {
+
+	turns := []Turn{}
+
+	for i := 1; i < len(route.Path)-1; i++ {
+		before := route.Path[i-1].Edges[route.Path[i].Id].Bearing
+		after := route.Path[i].Edges[route.Path[i+1].Id].Bearing
+
+		if bearingDifference(before, after) > turnAngle {
+			turns = append(turns, Turn{i, turnDirection(before, after)})
+		}
+	}
+
+	return turns
+}
+
+// TurnDirection returns the signed difference between two bearings, in the
+// range -180 to 180 and positive if b2 is clockwise from b1. Turning back is
+// -180.
+func turnDirection(b1, b2 float64) float64 {
+	return math.Mod(b2-b1+540, 360) - 180
+}
@@ -0,0 +1,68 @@
+package routing
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestTurnPoints(t *testing.T) {
+
+	// 6 - 7 - 8
+	// |   |   |
+	// 3 - 4 - 5
+	// |   |   |
+	// 0 - 1 - 2
+	g := gridGraph(3)
+
+	cases := []struct {
+		path []Id
+		want []Turn
+	}{
+		{[]Id{0, 1, 2}, []Turn{}},
+		{[]Id{0, 1, 2, 5}, []Turn{{2, -90}}},
+		{[]Id{0, 1, 4, 5, 8}, []Turn{{1, -90}, {2, 90}, {3, -90}}},
+		{[]Id{0, 1, 4, 3, 0}, []Turn{{1, -90}, {2, -90}, {3, -90}}},
+		{[]Id{0, 1, 0}, []Turn{{1, -180}}},
+		{[]Id{0}, []Turn{}},
+	}
+
+	for _, c := range cases {
+		route := routeFromPath(c.path, g, nil)
+		got := route.TurnPoints()
+
+		// angles are only checked to the nearest degree
+		for i := range got {
+			got[i].Angle = math.Round(got[i].Angle)
+		}
+
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%v.TurnPoints() == %v, want %v", c.path, got, c.want)
+		}
+
+		if len(got) != route.Turns {
+			t.Errorf("%v: %d turn points, route has %d turns", c.path, len(got), route.Turns)
+		}
+	}
+}
+
+func TestTurnDirection(t *testing.T) {
+
+	cases := []struct {
+		b1, b2 float64
+		want   float64
+	}{
+		{0, 90, 90},
+		{90, 0, -90},
+		{350, 10, 20},
+		{10, 350, -20},
+		{0, 0, 0},
+		{270, 180, -90},
+	}
+
+	for _, c := range cases {
+		if got := turnDirection(c.b1, c.b2); got != c.want {
+			t.Errorf("turnDirection(%v, %v) == %v, want %v", c.b1, c.b2, got, c.want)
+		}
+	}
+}
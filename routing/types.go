@@ -8,12 +8,129 @@ import (
 // Rotation enum type
 type Rotation int
 
-// Rotation option
+// Rotation option, Straight keeps the initial bearing.
 const (
 	Clockwise Rotation = iota
 	Anticlockwise
+	Straight
 )
 
+// Shape enum type
+type Shape int
+
+// Shape option
+const (
+	// Loop returns to the start along different paths.
+	Loop Shape = iota
+	// OutAndBack returns to the start the same way it went out.
+	OutAndBack
+	// Lollipop goes out along a stem, does a loop and comes back along the stem.
+	Lollipop
+	// MultiLoop is a number of loops through the start, two make a figure
+	// eight.
+	MultiLoop
+)
+
+// Shapes selectable by name.
+var Shapes = map[string]Shape{
+	"loop":       Loop,
+	"outandback": OutAndBack,
+	"lollipop":   Lollipop,
+	"multiloop":  MultiLoop,
+}
+
+// Fraction of the distance of a lollipop spent on the stem in each direction.
+const lollipopStem = 0.25
+
+// Reach returns how far from the start in a straight line a route of the
+// shape and given distance can get.
+func (shape Shape) Reach(distance float64) float64 {
+	switch shape {
+	case OutAndBack:
+		return distance / 2
+	case Lollipop:
+		return distance*lollipopStem + distance*(1-2*lollipopStem)/math.Pi
+	case MultiLoop:
+		// the loops are the largest when there are only two
+		return distance / 2 / math.Pi
+	}
+	return distance / math.Pi
+}
+
+// Options adjust how routes are generated and scored.
+type Options struct {
+	// Profile weights edges by their way attributes, nil treats all edges
+	// equally.
+	Profile *Profile
+
+	// Scorer ranks the routes, if nil DefaultScorer is used with weights
+	// adjusted by the profile.
+	Scorer Scorer
+
+	// Cost is used by A* searches, if nil edges are weighted by the profile.
+	Cost CostFunc
+
+	// SearchBudget limits the number of nodes a single A* search expands.
+	SearchBudget int
+
+	// Shape of the routes generated by TopRoutes.
+	Shape Shape
+
+	// Loops is the number of loops of a MultiLoop route, 2 if not set.
+	Loops int
+
+	// Waypoints are locations that loops have to pass through, in the given
+	// order if Ordered is set or in the order giving the shortest loop
+	// otherwise. Only loops support waypoints.
+	Waypoints []Waypoint
+	Ordered   bool
+}
+
+// Waypoint is a location given by latitude and longitude.
+type Waypoint struct {
+	Lat float64
+	Lon float64
+}
+
+// cost returns the CostFunc for A* searches.
+func (opts Options) cost() CostFunc {
+	if opts.Cost != nil {
+		return opts.Cost
+	}
+	return opts.Profile.Cost
+}
+
+// budget returns the search budget for A* searches.
+func (opts Options) budget() int {
+	if opts.SearchBudget > 0 {
+		return opts.SearchBudget
+	}
+	return defaultSearchBudget
+}
+
+// loops returns the number of loops of a MultiLoop route.
+func (opts Options) loops() int {
+	if opts.Loops > 0 {
+		return opts.Loops
+	}
+	return 2
+}
+
+// scorer returns the Scorer to rank routes with, base scaled by the profile
+// unless one was given.
+func (opts Options) scorer(base Scorer) Scorer {
+
+	if opts.Scorer != nil {
+		return opts.Scorer
+	}
+
+	if scorer, err := opts.Profile.Scorer(base); err == nil {
+		return scorer
+	}
+
+	return base
+}
+
 // Unique id identifying nodes
 type Id int
 
@@ -61,6 +178,76 @@ func (graph *Graph) RemoveDeadEnds() {
 	}
 }
 
+// Prune returns a copy of the graph without the edges for which drop returns
+// true and without the dead ends left behind. Drop has to treat both
+// directions of an edge alike. The graph itself is not modified, so it can be
+// shared by requests avoiding different edges.
+func (graph Graph) Prune(drop func(from, to *Node, e Edge) bool) Graph {
+
+	pruned := make(Graph, len(graph))
+
+	for id, node := range graph {
+		copied := &Node{
+			Id:        node.Id,
+			Lat:       node.Lat,
+			Lon:       node.Lon,
+			Elevation: node.Elevation,
+			Adjacent:  make([]Id, 0, len(node.Adjacent)),
+			Edges:     make(map[Id]Edge, len(node.Edges)),
+		}
+
+		for _, next := range node.Adjacent {
+			edge := node.Edges[next]
+			if graph[next] == nil || drop(node, graph[next], edge) {
+				continue
+			}
+			copied.Adjacent = append(copied.Adjacent, next)
+			copied.Edges[next] = edge
+		}
+
+		pruned[id] = copied
+	}
+
+	pruned.RemoveDeadEnds()
+
+	return pruned
+}
+
+// SetElevations sets the elevation of every node to the height returned by
+// the function and the gain and loss of every edge accordingly. Nodes for
+// which there is no height are NaN and their edges are treated as flat. It
+// returns the number of such nodes.
+func (graph Graph) SetElevations(height func(lat, lon float64) (float64, error)) (missing int) {
+
+	for _, node := range graph {
+		elevation, err := height(node.Lat, node.Lon)
+
+		if err != nil {
+			elevation = math.NaN()
+			missing++
+		}
+
+		node.Elevation = elevation
+	}
+
+	for _, node := range graph {
+		for id, edge := range node.Edges {
+			next, ok := graph[id]
+
+			edge.Gain, edge.Loss = 0, 0
+
+			if ok && !math.IsNaN(node.Elevation) && !math.IsNaN(next.Elevation) {
+				climb := next.Elevation - node.Elevation
+				edge.Gain, edge.Loss = math.Max(climb, 0), math.Max(-climb, 0)
+			}
+
+			node.Edges[id] = edge
+		}
+	}
+
+	return
+}
+
 // Node represents a vertex with latitude and longitude and stores a list of
 // edges connected to it.
 type Node struct {
@@ -68,6 +255,10 @@ type Node struct {
 	Lat float64
 	Lon float64
 
+	// Elevation in meters above sea level, NaN if it is unknown. It is only
+	// set once SetElevations was called on the graph.
+	Elevation float64
+
 	Adjacent []Id
 	Edges    map[Id]Edge
 }
@@ -86,10 +277,45 @@ func (node *Node) removeEdge(target Id) {
 	}
 }
 
-// Edge stores infomation on distance and and bearing between two nodes.
+// Edge stores infomation on distance and and bearing between two nodes as well
+// as attributes and OSM id of the way they are connected by. Gain and Loss
+// are the height in meters climbed and descended along the edge.
 type Edge struct {
 	Distance float64
 	Bearing  float64
+	Way      *Way
+	WayId    int
+	Gain     float64
+	Loss     float64
+}
+
+// Way stores a compact set of OSM tags of a way that are useful for scoring
+// routes and describing them. Missing tags are empty strings.
+type Way struct {
+	Highway    string
+	Surface    string
+	Smoothness string
+	Lit        string
+	Sidewalk   string
+	Name       string
+	Access     string
+	Incline    string
+}
+
+// Ways interns Way values so that all the edges with identical attributes,
+// such as the segments of a single way, point to the same copy.
+type Ways map[Way]*Way
+
+// Intern returns the shared copy of way.
+func (ways Ways) Intern(way Way) *Way {
+	if shared, ok := ways[way]; ok {
+		return shared
+	}
+
+	shared := &way
+	ways[way] = shared
+
+	return shared
 }
 
 // Route type stores information describing a route such as ordered slice of nodes that
@@ -101,6 +327,30 @@ type Route struct {
 	Visited       map[Id]int
 	RepeatVisits  int
 	Turns         int
+
+	// IntendedRepeats is the part of RepeatVisits that is due to the shape of
+	// the route, such as the way back of an out and back route.
+	IntendedRepeats int
+
+	// Boundaries holds the index in Path at which each loop of a MultiLoop
+	// route ends back at the start.
+	Boundaries []int
+
+	// Anchor is the index of the node in Path that an incomplete route has to
+	// return to, the nodes before it are retraced once it has.
+	anchor int
+
+	// Penalty is the sum of extra lengths given to the edges of the route by
+	// the profile it was created with.
+	Penalty float64
+
+	// Ascent and Descent are the total height gained and lost in meters.
+	Ascent  float64
+	Descent float64
+
+	// Score is the sum of the weighted components in Breakdown.
+	Score     float64
+	Breakdown map[string]float64
 }
 
 type Routes []Route
@@ -113,21 +363,8 @@ func (routes Routes) Swap(i, j int) {
 	routes[i], routes[j] = routes[j], routes[i]
 }
 
+// Less compares scores of the routes which have to be set beforehand, see
+// Scorer.
 func (routes Routes) Less(i, j int) bool {
-
-	turnsI := routes[i].Turns * 30
-	dFromStartI := Haversine(routes[i].Path[0], routes[i].Path[len(routes[i].Path)-1]) / 3
-	distanceDiffI := math.Abs(routes[i].DesiredLength-routes[i].Length) / 3
-	repeatsI := (routes[i].RepeatVisits * 10000) / len(routes[i].Path)
-
-	iScore := dFromStartI + float64(turnsI) + float64(repeatsI) + distanceDiffI
-
-	turnsJ := routes[j].Turns * 30
-	dFromStartJ := Haversine(routes[j].Path[0], routes[j].Path[len(routes[j].Path)-1]) / 3
-	distanceDiffJ := math.Abs(routes[j].DesiredLength-routes[j].Length) / 3
-	repeatsJ := (routes[j].RepeatVisits * 10000) / len(routes[j].Path)
-
-	jScore := dFromStartJ + float64(turnsJ) + float64(repeatsJ) + distanceDiffJ
-
-	return iScore < jScore
+	return routes[i].Score < routes[j].Score
 }
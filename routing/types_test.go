@@ -1,6 +1,8 @@
 package routing
 
 import (
+	"errors"
+	"math"
 	"reflect"
 	"testing"
 )
@@ -11,9 +13,9 @@ func TestRemoveEdge(t *testing.T) {
 		target Id
 		want   Node
 	}{
-		{Node{1, 0, 0, []Id{2, 3}, map[Id]Edge{2: {}, 3: {}}}, 4, Node{1, 0, 0, []Id{2, 3}, map[Id]Edge{2: {}, 3: {}}}},
-		{Node{1, 0, 0, []Id{2, 3}, map[Id]Edge{2: {}, 3: {}}}, 3, Node{1, 0, 0, []Id{2}, map[Id]Edge{2: {}}}},
-		{Node{1, 0, 0, []Id{}, nil}, 1, Node{1, 0, 0, []Id{}, nil}},
+		{Node{Id: 1, Lat: 0, Lon: 0, Adjacent: []Id{2, 3}, Edges: map[Id]Edge{2: {}, 3: {}}}, 4, Node{Id: 1, Lat: 0, Lon: 0, Adjacent: []Id{2, 3}, Edges: map[Id]Edge{2: {}, 3: {}}}},
+		{Node{Id: 1, Lat: 0, Lon: 0, Adjacent: []Id{2, 3}, Edges: map[Id]Edge{2: {}, 3: {}}}, 3, Node{Id: 1, Lat: 0, Lon: 0, Adjacent: []Id{2}, Edges: map[Id]Edge{2: {}}}},
+		{Node{Id: 1, Lat: 0, Lon: 0, Adjacent: []Id{}, Edges: nil}, 1, Node{Id: 1, Lat: 0, Lon: 0, Adjacent: []Id{}, Edges: nil}},
 	}
 
 	for _, c := range cases {
@@ -37,17 +39,17 @@ func TestRemoveDeadEnds(t *testing.T) {
 		{
 			// in
 			Graph{
-				1: &Node{1, 0, 0, []Id{2, 3, 4}, nil},
-				2: &Node{2, 0, 0, []Id{1, 3}, nil},
-				3: &Node{3, 0, 0, []Id{1, 2, 5}, nil},
-				4: &Node{4, 0, 0, []Id{1}, nil},
-				5: &Node{5, 0, 0, []Id{3}, nil},
+				1: &Node{Id: 1, Lat: 0, Lon: 0, Adjacent: []Id{2, 3, 4}, Edges: nil},
+				2: &Node{Id: 2, Lat: 0, Lon: 0, Adjacent: []Id{1, 3}, Edges: nil},
+				3: &Node{Id: 3, Lat: 0, Lon: 0, Adjacent: []Id{1, 2, 5}, Edges: nil},
+				4: &Node{Id: 4, Lat: 0, Lon: 0, Adjacent: []Id{1}, Edges: nil},
+				5: &Node{Id: 5, Lat: 0, Lon: 0, Adjacent: []Id{3}, Edges: nil},
 			},
 			// want
 			Graph{
-				1: &Node{1, 0, 0, []Id{2, 3}, nil},
-				2: &Node{2, 0, 0, []Id{1, 3}, nil},
-				3: &Node{3, 0, 0, []Id{1, 2}, nil},
+				1: &Node{Id: 1, Lat: 0, Lon: 0, Adjacent: []Id{2, 3}, Edges: nil},
+				2: &Node{Id: 2, Lat: 0, Lon: 0, Adjacent: []Id{1, 3}, Edges: nil},
+				3: &Node{Id: 3, Lat: 0, Lon: 0, Adjacent: []Id{1, 2}, Edges: nil},
 			},
 		},
 
@@ -55,17 +57,17 @@ func TestRemoveDeadEnds(t *testing.T) {
 		{
 			// in
 			Graph{
-				1: &Node{1, 0, 0, []Id{2, 3, 4}, nil},
-				2: &Node{2, 0, 0, []Id{1, 3}, nil},
-				3: &Node{3, 0, 0, []Id{1, 2}, nil},
-				4: &Node{4, 0, 0, []Id{1, 5}, nil},
-				5: &Node{5, 0, 0, []Id{4}, nil},
+				1: &Node{Id: 1, Lat: 0, Lon: 0, Adjacent: []Id{2, 3, 4}, Edges: nil},
+				2: &Node{Id: 2, Lat: 0, Lon: 0, Adjacent: []Id{1, 3}, Edges: nil},
+				3: &Node{Id: 3, Lat: 0, Lon: 0, Adjacent: []Id{1, 2}, Edges: nil},
+				4: &Node{Id: 4, Lat: 0, Lon: 0, Adjacent: []Id{1, 5}, Edges: nil},
+				5: &Node{Id: 5, Lat: 0, Lon: 0, Adjacent: []Id{4}, Edges: nil},
 			},
 			// want
 			Graph{
-				1: &Node{1, 0, 0, []Id{2, 3}, nil},
-				2: &Node{2, 0, 0, []Id{1, 3}, nil},
-				3: &Node{3, 0, 0, []Id{1, 2}, nil},
+				1: &Node{Id: 1, Lat: 0, Lon: 0, Adjacent: []Id{2, 3}, Edges: nil},
+				2: &Node{Id: 2, Lat: 0, Lon: 0, Adjacent: []Id{1, 3}, Edges: nil},
+				3: &Node{Id: 3, Lat: 0, Lon: 0, Adjacent: []Id{1, 2}, Edges: nil},
 			},
 		},
 
@@ -73,10 +75,10 @@ func TestRemoveDeadEnds(t *testing.T) {
 		{
 			// in
 			Graph{
-				1: &Node{1, 0, 0, []Id{2}, nil},
-				2: &Node{2, 0, 0, []Id{1, 3}, nil},
-				3: &Node{3, 0, 0, []Id{2, 4}, nil},
-				4: &Node{4, 0, 0, []Id{3}, nil},
+				1: &Node{Id: 1, Lat: 0, Lon: 0, Adjacent: []Id{2}, Edges: nil},
+				2: &Node{Id: 2, Lat: 0, Lon: 0, Adjacent: []Id{1, 3}, Edges: nil},
+				3: &Node{Id: 3, Lat: 0, Lon: 0, Adjacent: []Id{2, 4}, Edges: nil},
+				4: &Node{Id: 4, Lat: 0, Lon: 0, Adjacent: []Id{3}, Edges: nil},
 			},
 			// want
 			Graph{},
@@ -86,7 +88,7 @@ func TestRemoveDeadEnds(t *testing.T) {
 		{
 			// in
 			Graph{
-				1: &Node{1, 0, 0, []Id{}, nil},
+				1: &Node{Id: 1, Lat: 0, Lon: 0, Adjacent: []Id{}, Edges: nil},
 			},
 			// want
 			Graph{},
@@ -104,18 +106,18 @@ func TestRemoveDeadEnds(t *testing.T) {
 		{
 			// in
 			Graph{
-				1: &Node{1, 0, 0, []Id{2, 3, 4}, nil},
-				2: &Node{2, 0, 0, []Id{1, 3}, nil},
-				3: &Node{3, 0, 0, []Id{1, 2}, nil},
-				4: &Node{4, 0, 0, []Id{1, 5, 6}, nil},
-				5: &Node{5, 0, 0, []Id{4}, nil},
-				6: &Node{6, 0, 0, []Id{4}, nil},
+				1: &Node{Id: 1, Lat: 0, Lon: 0, Adjacent: []Id{2, 3, 4}, Edges: nil},
+				2: &Node{Id: 2, Lat: 0, Lon: 0, Adjacent: []Id{1, 3}, Edges: nil},
+				3: &Node{Id: 3, Lat: 0, Lon: 0, Adjacent: []Id{1, 2}, Edges: nil},
+				4: &Node{Id: 4, Lat: 0, Lon: 0, Adjacent: []Id{1, 5, 6}, Edges: nil},
+				5: &Node{Id: 5, Lat: 0, Lon: 0, Adjacent: []Id{4}, Edges: nil},
+				6: &Node{Id: 6, Lat: 0, Lon: 0, Adjacent: []Id{4}, Edges: nil},
 			},
 			// want
 			Graph{
-				1: &Node{1, 0, 0, []Id{2, 3}, nil},
-				2: &Node{2, 0, 0, []Id{1, 3}, nil},
-				3: &Node{3, 0, 0, []Id{1, 2}, nil},
+				1: &Node{Id: 1, Lat: 0, Lon: 0, Adjacent: []Id{2, 3}, Edges: nil},
+				2: &Node{Id: 2, Lat: 0, Lon: 0, Adjacent: []Id{1, 3}, Edges: nil},
+				3: &Node{Id: 3, Lat: 0, Lon: 0, Adjacent: []Id{1, 2}, Edges: nil},
 			},
 		},
 	}
@@ -131,3 +133,54 @@ func TestRemoveDeadEnds(t *testing.T) {
 		}
 	}
 }
+
+func TestSetElevations(t *testing.T) {
+
+	//  1 - 2 - 3
+	//  |   |   |
+	//  4 - 5 - 6
+	g := testGraph(
+		map[Id][2]float64{1: {1, 0}, 2: {1, 1}, 3: {1, 2}, 4: {0, 0}, 5: {0, 1}, 6: {0, 2}},
+		[][2]Id{{1, 2}, {2, 3}, {1, 4}, {4, 5}, {5, 6}, {3, 6}, {2, 5}},
+		nil,
+	)
+
+	// the top row is 10m higher, there is no data in the east
+	missing := g.SetElevations(func(lat, lon float64) (float64, error) {
+		if lon > g[2].Lon {
+			return 0, errors.New("no data")
+		}
+		if lat > g[4].Lat {
+			return 60, nil
+		}
+		return 50, nil
+	})
+
+	if missing != 2 || !math.IsNaN(g[3].Elevation) || g[1].Elevation != 60 {
+		t.Errorf("SetElevations() == %v, elevations %v, %v", missing, g[1].Elevation, g[3].Elevation)
+	}
+
+	cases := []struct {
+		from, to   Id
+		gain, loss float64
+	}{
+		{4, 1, 10, 0},
+		{1, 4, 0, 10},
+		{1, 2, 0, 0},
+		{2, 3, 0, 0},
+	}
+
+	for _, c := range cases {
+		e := g[c.from].Edges[c.to]
+
+		if e.Gain != c.gain || e.Loss != c.loss {
+			t.Errorf("edge %v-%v gain, loss == %v, %v, want %v, %v", c.from, c.to, e.Gain, e.Loss, c.gain, c.loss)
+		}
+	}
+
+	route := routeFromPath([]Id{1, 2, 5, 4, 1}, g, nil)
+
+	if route.Ascent != 10 || route.Descent != 10 {
+		t.Errorf("route ascent, descent == %v, %v, want 10, 10", route.Ascent, route.Descent)
+	}
+}
@@ -0,0 +1,148 @@
+package routing
+
+import (
+	"math"
+	"sort"
+)
+
+// Share of the distance below which a loop through the waypoints is not
+// padded with a detour, and by which it may be exceeded when the waypoints
+// cannot be visited otherwise.
+const minPadding = 0.1
+
+// ViaRoutes returns loops that pass through the waypoints of opts. The
+// waypoints are connected to the start with A* and the remaining distance is
+// made up by a loop generated as in TopRoutes, which is inserted at the start
+// or one of the waypoints. If the waypoints cannot be visited within the
+// distance no routes are returned.
+func viaRoutes(lat, lon, distance float64, graph Graph, opts Options) Routes {
+
+	via := make([]*Node, 0, len(opts.Waypoints))
+
+	for _, w := range opts.Waypoints {
+		closest := ClosestNodes(w.Lat, w.Lon, graph, 1)
+		if len(closest) == 0 {
+			return Routes{}
+		}
+		via = append(via, closest[0])
+	}
+
+	top := make(Routes, 0, 25)
+	scorer := opts.scorer(DefaultScorer)
+
+	for _, start := range ClosestNodes(lat, lon, graph, 3) {
+		stops := via
+		if !opts.Ordered {
+			stops = shortestOrder(start, via)
+		}
+
+		ids, err := connect(append(append([]*Node{start}, stops...), start), graph, opts)
+
+		if err != nil {
+			continue
+		}
+
+		skeleton := routeFromPath(ids, graph, opts.Profile)
+		remaining := distance - skeleton.Length
+
+		if remaining < -distance*minPadding {
+			continue
+		}
+
+		if remaining < distance*minPadding {
+			skeleton.DesiredLength = distance
+			scorer.Score(&skeleton)
+			top = appendRoute(skeleton, top)
+			continue
+		}
+
+		loopOpts := opts
+		loopOpts.Shape = Loop
+		loopOpts.Waypoints = nil
+
+		for _, pad := range topRoutesFrom(append([]*Node{start}, stops...), remaining, graph, loopOpts) {
+			route := routeFromPath(splice(ids, pathIds(pad.Path)), graph, opts.Profile)
+			route.DesiredLength = distance
+			scorer.Score(&route)
+			top = appendRoute(route, top)
+		}
+	}
+
+	sort.Sort(top)
+
+	return top
+}
+
+// Connect returns the ids of nodes on the cheapest path through the stops.
+func connect(stops []*Node, graph Graph, opts Options) ([]Id, error) {
+
+	ids := []Id{stops[0].Id}
+
+	for i := 1; i < len(stops); i++ {
+		segment, err := aStar(stops[i-1], stops[i], graph, opts.cost().edgeCost(), opts.budget())
+
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, segment[1:]...)
+	}
+
+	return ids, nil
+}
+
+// Splice inserts a loop into the path at the first node the loop starts at.
+func splice(path, loop []Id) []Id {
+
+	for i, id := range path {
+		if id == loop[0] {
+			spliced := append([]Id{}, path[:i]...)
+			spliced = append(spliced, loop...)
+			return append(spliced, path[i+1:]...)
+		}
+	}
+
+	return path
+}
+
+// ShortestOrder returns the waypoints in the order that gives the shortest
+// straight line loop from the start through all of them.
+func shortestOrder(start *Node, via []*Node) []*Node {
+
+	best := append([]*Node{}, via...)
+	bestLength := math.Inf(1)
+
+	permute(append([]*Node{}, via...), 0, func(order []*Node) {
+		length := 0.0
+		previous := start
+
+		for _, n := range order {
+			length += Haversine(previous, n)
+			previous = n
+		}
+
+		length += Haversine(previous, start)
+
+		if length < bestLength {
+			bestLength = length
+			copy(best, order)
+		}
+	})
+
+	return best
+}
+
+// Permute calls fn with every permutation of nodes from index k onwards.
+func permute(nodes []*Node, k int, fn func([]*Node)) {
+
+	if k == len(nodes) {
+		fn(nodes)
+		return
+	}
+
+	for i := k; i < len(nodes); i++ {
+		nodes[k], nodes[i] = nodes[i], nodes[k]
+		permute(nodes, k+1, fn)
+		nodes[k], nodes[i] = nodes[i], nodes[k]
+	}
+}
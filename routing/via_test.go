@@ -0,0 +1,75 @@
+package routing
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplice(t *testing.T) {
+
+	cases := []struct {
+		path, loop []Id
+		want       []Id
+	}{
+		{[]Id{1, 2, 3, 1}, []Id{2, 5, 6, 2}, []Id{1, 2, 5, 6, 2, 3, 1}},
+		{[]Id{1, 2, 3, 1}, []Id{1, 7, 1}, []Id{1, 7, 1, 2, 3, 1}},
+		{[]Id{1, 2, 3, 1}, []Id{4, 7, 4}, []Id{1, 2, 3, 1}},
+	}
+
+	for _, c := range cases {
+		result := splice(c.path, c.loop)
+
+		if !reflect.DeepEqual(result, c.want) {
+			t.Errorf("splice(%v, %v) == %v, want %v", c.path, c.loop, result, c.want)
+		}
+	}
+}
+
+func TestShortestOrder(t *testing.T) {
+
+	g := gridGraph(5)
+
+	// going 0 - 4 - 24 - 20 - 0 is shorter than crossing the grid
+	result := pathIds(shortestOrder(g[0], []*Node{g[24], g[20], g[4]}))
+
+	if want := []Id{4, 24, 20}; !reflect.DeepEqual(result, want) && !reflect.DeepEqual(result, []Id{20, 24, 4}) {
+		t.Errorf("shortestOrder() == %v, want %v or reversed", result, want)
+	}
+}
+
+func TestViaRoutes(t *testing.T) {
+
+	g := gridGraph(12)
+	start := g[66]
+	via := []*Node{g[69], g[30]}
+
+	waypoints := []Waypoint{}
+	for _, n := range via {
+		waypoints = append(waypoints, Waypoint{n.Lat, n.Lon})
+	}
+
+	for _, ordered := range []bool{false, true} {
+		routes := TopRoutes(start.Lat, start.Lon, 2500, g, Options{Waypoints: waypoints, Ordered: ordered})
+
+		if len(routes) == 0 {
+			t.Fatalf("ordered %v: no routes returned", ordered)
+		}
+
+		for _, r := range routes {
+			if r.Path[0] != r.Path[len(r.Path)-1] {
+				t.Errorf("ordered %v: route %v does not end at the start", ordered, pathIds(r.Path))
+			}
+
+			for _, n := range via {
+				if r.Visited[n.Id] == 0 {
+					t.Errorf("ordered %v: route %v does not visit %v", ordered, pathIds(r.Path), n.Id)
+				}
+			}
+		}
+	}
+
+	// the waypoints are too far apart for a short loop
+	if routes := TopRoutes(start.Lat, start.Lon, 500, g, Options{Waypoints: waypoints}); len(routes) != 0 {
+		t.Errorf("TopRoutes() returned %d routes that cannot visit the waypoints", len(routes))
+	}
+}
@@ -0,0 +1,125 @@
+package routeplanner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/yurachistic1/routeplanner-backend/osmfile"
+	"github.com/yurachistic1/routeplanner-backend/overpass"
+	"github.com/yurachistic1/routeplanner-backend/routing"
+)
+
+// ErrNoData is returned by a DataSource when there is no map data at all for
+// the requested area.
+var ErrNoData = errors.New("no map data for the area")
+
+// DataSource provides a graph of paths suitable for pedestrians within an area.
+type DataSource interface {
+	Fetch(ctx context.Context, area overpass.Area) (routing.Graph, error)
+}
+
+// OverpassSource downloads map data from overpass api instances.
+type OverpassSource struct {
+	Client *overpass.Client
+	Filter overpass.Filter
+}
+
+func (s *OverpassSource) Fetch(ctx context.Context, area overpass.Area) (routing.Graph, error) {
+	builder := NewGraphBuilder()
+	_, err := s.Client.Stream(ctx, s.Filter.QL(area), builder.Add)
+	return builderToGraph(builder, err)
+}
+
+// CachedSource downloads map data from overpass api instances and keeps it in
+// a tile cache so that requests for nearby areas are answered from disk. The
+// tiles are clipped to the requested area, so a disc only yields the ways that
+// reach into it, as with an (around:) filter.
+type CachedSource struct {
+	Cache  *overpass.TileCache
+	Filter overpass.Filter
+}
+
+func (s *CachedSource) Fetch(ctx context.Context, area overpass.Area) (routing.Graph, error) {
+	builder := NewGraphBuilder()
+	_, err := s.Cache.Stream(ctx, area, s.Filter, builder.Add)
+	return builderToGraph(builder, err)
+}
+
+// FileSource reads map data from a local file. Files ending in .json are
+// expected to contain a saved overpass response and are used as a whole,
+// anything else is read as an OSM extract and only the ways selected by Filter
+// are used. The file is read on the first Fetch and kept in memory.
+type FileSource struct {
+	Path   string
+	Filter overpass.Filter
+
+	once     sync.Once
+	elements []overpass.Element
+	extract  *osmfile.Extract
+	err      error
+}
+
+func (s *FileSource) Fetch(ctx context.Context, area overpass.Area) (routing.Graph, error) {
+
+	s.once.Do(s.load)
+
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	builder := NewGraphBuilder()
+	elements := s.elements
+
+	if s.extract != nil {
+		elements = s.extract.Response(area).Elements
+	}
+
+	for i := range elements {
+		builder.Add(&elements[i])
+	}
+
+	return builderToGraph(builder, nil)
+}
+
+// Load reads the file of the source.
+func (s *FileSource) load() {
+
+	if !strings.HasSuffix(s.Path, ".json") {
+		s.extract, s.err = osmfile.Load(s.Path, s.Filter)
+		return
+	}
+
+	f, err := os.Open(s.Path)
+
+	if err != nil {
+		s.err = err
+		return
+	}
+
+	defer f.Close()
+
+	_, s.err = overpass.DecodeElements(f, func(e *overpass.Element) error {
+		s.elements = append(s.elements, *e)
+		return nil
+	})
+}
+
+// BuilderToGraph returns the graph assembled by builder unless there was an
+// error getting the data or there is nothing to walk on in it.
+func builderToGraph(builder *GraphBuilder, err error) (routing.Graph, error) {
+
+	if err != nil {
+		return nil, err
+	}
+
+	graph := builder.Graph()
+
+	if len(graph) == 0 {
+		return nil, ErrNoData
+	}
+
+	return graph, nil
+}
@@ -0,0 +1,120 @@
+package routeplanner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yurachistic1/routeplanner-backend/overpass"
+)
+
+// gridServer answers tile queries with the whole test grid for every tile.
+func gridServer(t *testing.T) *httptest.Server {
+
+	data, err := ioutil.ReadFile("testdata/grid.json")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var grid struct {
+		Elements []json.RawMessage
+	}
+
+	if err := json.Unmarshal(data, &grid); err != nil {
+		t.Fatal(err)
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		elements := []string{}
+
+		for i := 0; i < strings.Count(r.FormValue("data"), "make tile"); i++ {
+			elements = append(elements, fmt.Sprintf(`{"type": "tile", "id": %d, "tags": {"index": "%d"}}`, i+1, i))
+			for _, e := range grid.Elements {
+				elements = append(elements, string(e))
+			}
+		}
+
+		fmt.Fprintf(w, `{"version": 0.6, "elements": [%s]}`, strings.Join(elements, ","))
+	}))
+}
+
+func TestCachedSourceAround(t *testing.T) {
+
+	server := gridServer(t)
+	defer server.Close()
+
+	source := &CachedSource{
+		Cache:  overpass.NewTileCache(overpass.NewClient(server.URL), t.TempDir()),
+		Filter: overpass.Pedestrian,
+	}
+
+	// a disc around the south west corner of the grid reaching its neighbours
+	graph, err := source.Fetch(context.Background(), overpass.Around{Lat: 51.266, Lon: 0.184, Radius: 150})
+
+	if err != nil {
+		t.Fatalf("Fetch() returned error %v", err)
+	}
+
+	if len(graph) == 0 {
+		t.Fatalf("Fetch() returned an empty graph")
+	}
+
+	// only the first two rows and columns reach into the disc
+	for id := range graph {
+		row, col := (id-1)/9, (id-1)%9
+		if row > 1 && col > 1 {
+			t.Errorf("Fetch() returned node %d outside of the ways reaching the disc", id)
+		}
+	}
+}
+
+func TestFileSourceReadsOnce(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "extract.osm")
+
+	doc := `<osm version="0.6">
+  <node id="1" lat="51.500" lon="-0.100"/>
+  <node id="2" lat="51.501" lon="-0.100"/>
+  <node id="3" lat="51.501" lon="-0.101"/>
+  <node id="4" lat="51.600" lon="-0.100"/>
+  <node id="5" lat="51.601" lon="-0.100"/>
+  <node id="6" lat="51.601" lon="-0.101"/>
+  <way id="10">
+    <nd ref="1"/><nd ref="2"/><nd ref="3"/><nd ref="1"/>
+    <tag k="highway" v="footway"/>
+  </way>
+  <way id="11">
+    <nd ref="4"/><nd ref="5"/><nd ref="6"/><nd ref="4"/>
+    <tag k="highway" v="footway"/>
+  </way>
+</osm>`
+
+	if err := ioutil.WriteFile(path, []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	source := &FileSource{Path: path, Filter: overpass.Pedestrian}
+
+	graph, err := source.Fetch(context.Background(), overpass.Around{Lat: 51.5, Lon: -0.1, Radius: 500})
+
+	if err != nil || len(graph) != 3 || graph[1] == nil {
+		t.Fatalf("Fetch() == %v, %v, want the first triangle", graph, err)
+	}
+
+	// later requests are answered from memory
+	os.Remove(path)
+
+	graph, err = source.Fetch(context.Background(), overpass.Around{Lat: 51.6, Lon: -0.1, Radius: 500})
+
+	if err != nil || len(graph) != 3 || graph[4] == nil {
+		t.Errorf("Fetch() == %v, %v, want the second triangle", graph, err)
+	}
+}
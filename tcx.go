@@ -0,0 +1,126 @@
+package routeplanner
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/yurachistic1/routeplanner-backend/routing"
+)
+
+// Tcx is the root element of a Training Center XML document.
+type tcx struct {
+	XMLName xml.Name    `xml:"http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2 TrainingCenterDatabase"`
+	Courses []tcxCourse `xml:"Courses>Course"`
+}
+
+type tcxCourse struct {
+	Name        string           `xml:"Name"`
+	Lap         tcxLap           `xml:"Lap"`
+	Track       []tcxTrackpoint  `xml:"Track>Trackpoint"`
+	CoursePoint []tcxCoursePoint `xml:"CoursePoint"`
+}
+
+type tcxLap struct {
+	TotalTimeSeconds float64     `xml:"TotalTimeSeconds"`
+	DistanceMeters   float64     `xml:"DistanceMeters"`
+	BeginPosition    tcxPosition `xml:"BeginPosition"`
+	EndPosition      tcxPosition `xml:"EndPosition"`
+	Intensity        string      `xml:"Intensity"`
+}
+
+type tcxPosition struct {
+	Lat float64 `xml:"LatitudeDegrees"`
+	Lon float64 `xml:"LongitudeDegrees"`
+}
+
+type tcxTrackpoint struct {
+	Time           string      `xml:"Time"`
+	Position       tcxPosition `xml:"Position"`
+	AltitudeMeters *float64    `xml:"AltitudeMeters,omitempty"`
+	DistanceMeters float64     `xml:"DistanceMeters"`
+}
+
+type tcxCoursePoint struct {
+	Name      string      `xml:"Name"`
+	Time      string      `xml:"Time"`
+	Position  tcxPosition `xml:"Position"`
+	PointType string      `xml:"PointType"`
+}
+
+// EncodeTCX writes the routes as TCX courses with a course point at every
+// turn, which devices use to give turn cues.
+func encodeTCX(w io.Writer, out output) error {
+
+	doc := tcx{}
+
+	for i, route := range out.Routes {
+		doc.Courses = append(doc.Courses, tcxCourseOf(route, out.Ranks[i], out))
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	return encoder.Encode(doc)
+}
+
+// TcxCourseOf returns the course following the route.
+func tcxCourseOf(route routing.Route, rank int, out output) tcxCourse {
+
+	distances := route.Distances()
+	first, last := route.Path[0], route.Path[len(route.Path)-1]
+
+	course := tcxCourse{
+		Name: fmt.Sprintf("Route %d", rank+1),
+		Lap: tcxLap{
+			TotalTimeSeconds: route.Length / courseSpeed,
+			DistanceMeters:   route.Length,
+			BeginPosition:    tcxPosition{first.Lat, first.Lon},
+			EndPosition:      tcxPosition{last.Lat, last.Lon},
+			Intensity:        "Active",
+		},
+		Track:       make([]tcxTrackpoint, len(route.Path)),
+		CoursePoint: []tcxCoursePoint{},
+	}
+
+	for i, node := range route.Path {
+		course.Track[i] = tcxTrackpoint{
+			Time:           tcxTime(courseTime(out.Start, distances[i])),
+			Position:       tcxPosition{node.Lat, node.Lon},
+			DistanceMeters: distances[i],
+		}
+
+		if out.Elevation && !math.IsNaN(node.Elevation) {
+			ele := node.Elevation
+			course.Track[i].AltitudeMeters = &ele
+		}
+	}
+
+	for _, turn := range route.TurnPoints() {
+		node := route.Path[turn.Index]
+		point := tcxCoursePoint{
+			Name:      "Right",
+			Time:      tcxTime(courseTime(out.Start, distances[turn.Index])),
+			Position:  tcxPosition{node.Lat, node.Lon},
+			PointType: "Right",
+		}
+
+		if turn.Left() {
+			point.Name, point.PointType = "Left", "Left"
+		}
+
+		course.CoursePoint = append(course.CoursePoint, point)
+	}
+
+	return course
+}
+
+func tcxTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,63 @@
+package routeplanner
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeTCX(t *testing.T) {
+
+	var b bytes.Buffer
+
+	start := time.Date(2021, 6, 1, 8, 0, 0, 0, time.UTC)
+	out := output{Routes: testRoutes(), Ranks: []int{0, 1}, Elevation: true, Start: start}
+
+	if err := encodeTCX(&b, out); err != nil {
+		t.Fatalf("encodeTCX() returned %v", err)
+	}
+
+	if !strings.Contains(b.String(), `xmlns="http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2"`) {
+		t.Errorf("encodeTCX() == %s, want a TCX document", b.String())
+	}
+
+	var doc tcx
+
+	if err := xml.Unmarshal(b.Bytes(), &doc); err != nil {
+		t.Fatalf("encodeTCX() output is not valid XML: %v", err)
+	}
+
+	if len(doc.Courses) != 2 {
+		t.Fatalf("encodeTCX() == %d courses, want 2", len(doc.Courses))
+	}
+
+	course := doc.Courses[0]
+	route := out.Routes[0]
+
+	if course.Name != "Route 1" || course.Lap.DistanceMeters != route.Length || len(course.Track) != len(route.Path) {
+		t.Errorf("course == %+v", course)
+	}
+
+	last := course.Track[len(course.Track)-1]
+
+	if course.Track[0].Time != "2021-06-01T08:00:00Z" || last.Time != "2021-06-01T08:01:20Z" {
+		t.Errorf("track times == %v ... %v, want 08:00:00 ... 08:01:20", course.Track[0].Time, last.Time)
+	}
+
+	if course.Track[0].AltitudeMeters == nil || *course.Track[0].AltitudeMeters != 100 || last.AltitudeMeters != nil {
+		t.Errorf("track altitudes == %v ... %v", course.Track[0].AltitudeMeters, last.AltitudeMeters)
+	}
+
+	// the first route heads north and turns right
+	want := tcxCoursePoint{"Right", course.Track[1].Time, course.Track[1].Position, "Right"}
+
+	if len(course.CoursePoint) != 1 || course.CoursePoint[0] != want {
+		t.Errorf("course points == %+v, want %+v", course.CoursePoint, want)
+	}
+
+	if len(doc.Courses[1].CoursePoint) != 0 {
+		t.Errorf("course points == %+v, want none", doc.Courses[1].CoursePoint)
+	}
+}